@@ -0,0 +1,104 @@
+package mdbx
+
+import (
+	"log"
+	"sync"
+)
+
+// AutoGrow is a policy growing the datafile when a write transaction fails
+// because the map is full.  The failed TxnOp is retried once after the
+// datafile has grown.
+//
+// MDBX grows the datafile by itself unless the growth step set with
+// SetGeometry is zero, so an AutoGrow policy mostly applies to fixed size
+// geometries, such as the one set by SetMapSize.  The datafile never grows
+// past the upper bound set with SetGeometry, which is reserved in the address
+// space when the environment is opened.  When the datafile cannot grow the
+// failure is logged and the original error is returned.
+type AutoGrow struct {
+	// Step is the number of bytes added to the datafile each time it grows.
+	Step int
+
+	// Max is the hard ceiling of the datafile size.  Zero, or any value above
+	// the upper bound set with SetGeometry, stands for that upper bound.
+	Max int
+
+	// OnGrow is called each time the datafile grows.  If OnGrow is nil the
+	// growth is logged with the standard logger.
+	OnGrow func(GrowEvent)
+}
+
+// GrowEvent describes a growth of the datafile by an AutoGrow policy.
+type GrowEvent struct {
+	From int   // The previous size of the datafile
+	To   int   // The new size of the datafile
+	Err  error // The error which triggered the growth
+}
+
+type autoGrower struct {
+	mu     sync.Mutex
+	policy *AutoGrow
+}
+
+// SetAutoGrow sets the policy applied to write transactions which fail with
+// MapFull or UnableExtendMapsize, such as those run by Update, UpdateLocked,
+// Batch and Writer.  A nil policy disables automatic growth.  Transactions
+// begun with BeginTxn are not retried.
+//
+// Only the current size of the geometry is changed, the other settings passed
+// to SetGeometry are kept.
+func (env *Env) SetAutoGrow(policy *AutoGrow) {
+	env.grow.mu.Lock()
+	env.grow.policy = policy
+	env.grow.mu.Unlock()
+}
+
+func isMapFull(err error) bool {
+	return IsMapFull(err) || IsErrno(err, UnableExtendMapsize)
+}
+
+// growMap grows the datafile according to the AutoGrow policy of env after a
+// write transaction failed with cause.  It reports whether the datafile has
+// grown.
+func (env *Env) growMap(cause error) bool {
+	env.grow.mu.Lock()
+	defer env.grow.mu.Unlock()
+	policy := env.grow.policy
+	if policy == nil || policy.Step <= 0 {
+		return false
+	}
+
+	info, err := env.Info()
+	if err != nil {
+		return false
+	}
+	limit := int(info.Geo.Upper)
+	if policy.Max > 0 && policy.Max < limit {
+		limit = policy.Max
+	}
+	from := int(info.Geo.Current)
+	to := from + policy.Step
+	if to > limit {
+		to = limit
+	}
+	if to <= from {
+		return false
+	}
+	// a zero pagesize keeps the page size of an open environment
+	if err := env.SetGeometry(-1, to, int(info.Geo.Upper), -1, -1, 0); err != nil {
+		log.Printf("mdbx: cannot grow the datafile from %d to %d bytes: %v", from, to, err)
+		return false
+	}
+
+	ev := GrowEvent{From: from, To: to, Err: cause}
+	if info, err := env.Info(); err == nil {
+		// the size is rounded by MDBX
+		ev.To = int(info.Geo.Current)
+	}
+	if policy.OnGrow != nil {
+		policy.OnGrow(ev)
+	} else {
+		log.Printf("mdbx: datafile grown from %d to %d bytes after %v", ev.From, ev.To, cause)
+	}
+	return true
+}
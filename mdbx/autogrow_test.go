@@ -0,0 +1,85 @@
+package mdbx
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestEnv_AutoGrow(t *testing.T) {
+	path, err := ioutil.TempDir("", "mdbx_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+	env, err := NewEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer env.Close()
+	if err = env.OpenWithOptions(path, OpenOptions{TLS: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	const mb = 1 << 20
+	// a zero growth step keeps the datafile at its current size
+	if err := env.SetGeometry(-1, mb, 8*mb, 0, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	put := func(key string, size int) error {
+		return env.Update(func(txn *Txn) error {
+			dbi, err := txn.OpenRoot(0)
+			if err != nil {
+				return err
+			}
+			return txn.Put(dbi, []byte(key), make([]byte, size), 0)
+		})
+	}
+	if err := put("a", 2*mb); !isMapFull(err) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var events []GrowEvent
+	env.SetAutoGrow(&AutoGrow{
+		Step:   2 * mb,
+		Max:    4 * mb,
+		OnGrow: func(ev GrowEvent) { events = append(events, ev) },
+	})
+	if err := put("a", 2*mb); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].From != mb || events[0].To != 3*mb || !isMapFull(events[0].Err) {
+		t.Errorf("unexpected events: %+v", events)
+	}
+
+	// Max is reached after the second growth
+	if err := put("b", 3*mb); !isMapFull(err) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(events) != 2 || events[1].To != 4*mb {
+		t.Errorf("unexpected events: %+v", events)
+	}
+
+	// the upper bound is the ceiling of a policy without Max
+	events = nil
+	env.SetAutoGrow(&AutoGrow{
+		Step:   16 * mb,
+		OnGrow: func(ev GrowEvent) { events = append(events, ev) },
+	})
+	if err := put("b", 3*mb); err != nil {
+		t.Fatal(err)
+	}
+	if err := put("c", 6*mb); !isMapFull(err) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(events) != 1 || events[0].From != 4*mb || events[0].To != 8*mb {
+		t.Errorf("unexpected events: %+v", events)
+	}
+	info, err := env.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Geo.Current != 8*mb || info.Geo.Upper != 8*mb {
+		t.Errorf("geometry %+v (!= %d)", info.Geo, 8*mb)
+	}
+}
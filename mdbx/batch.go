@@ -0,0 +1,161 @@
+package mdbx
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Defaults for the coalescing of Env.Batch calls.
+const (
+	DefaultMaxBatchSize  = 1000
+	DefaultMaxBatchDelay = 10 * time.Millisecond
+)
+
+// batcher holds the pending Env.Batch calls of an Env and its settings.
+type batcher struct {
+	mu       sync.Mutex
+	pending  *batch
+	maxSize  int
+	maxDelay time.Duration
+}
+
+type batch struct {
+	env   *Env
+	timer *time.Timer
+	start sync.Once
+	calls []batchCall
+}
+
+type batchCall struct {
+	fn  TxnOp
+	err chan<- error
+}
+
+// SetMaxBatchSize sets the maximum number of calls to Batch coalesced in a
+// single transaction.  Zero restores DefaultMaxBatchSize.
+func (env *Env) SetMaxBatchSize(n int) {
+	env.batches.mu.Lock()
+	env.batches.maxSize = n
+	env.batches.mu.Unlock()
+}
+
+// SetMaxBatchDelay sets the maximum time a call to Batch waits for others to
+// join its transaction.  Zero restores DefaultMaxBatchDelay.
+func (env *Env) SetMaxBatchDelay(d time.Duration) {
+	env.batches.mu.Lock()
+	env.batches.maxDelay = d
+	env.batches.mu.Unlock()
+}
+
+// Batch calls fn as part of a write transaction shared with other concurrent
+// calls to Batch, which amortizes the cost of a commit over many small
+// updates.  Calls are collected until their number reaches the maximum batch
+// size (see SetMaxBatchSize) or the first of them has waited for the maximum
+// batch delay (see SetMaxBatchDelay).  Batch returns once the shared
+// transaction has been committed.
+//
+// Each fn runs in its own Txn.Sub, so if it fails only its changes are rolled
+// back.  A failed fn is then retried alone in a transaction of its own, and
+// its error is returned only to its caller.  A panic in fn is recovered and
+// returned as an error, see IsPanic.  Because fn may be called more than
+// once it must be idempotent and its side effects outside of the transaction
+// must only take effect once Batch returns successfully.
+//
+// Batch is only useful when called from many goroutines.  It may be called
+// from any goroutine, the transaction is run by a goroutine locked to its
+// thread.  Environments opened with WriteMap do not support nested
+// transactions, in which case every fn ends up in a transaction of its own.
+func (env *Env) Batch(fn TxnOp) error {
+	errc := make(chan error, 1)
+
+	b := &env.batches
+	b.mu.Lock()
+	maxSize := b.maxSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxBatchSize
+	}
+	if b.pending == nil || len(b.pending.calls) >= maxSize {
+		delay := b.maxDelay
+		if delay <= 0 {
+			delay = DefaultMaxBatchDelay
+		}
+		p := &batch{env: env}
+		p.timer = time.AfterFunc(delay, p.trigger)
+		b.pending = p
+	}
+	p := b.pending
+	p.calls = append(p.calls, batchCall{fn: fn, err: errc})
+	if len(p.calls) >= maxSize {
+		// wake up the batch, it's ready to run
+		go p.trigger()
+	}
+	b.mu.Unlock()
+
+	return <-errc
+}
+
+// trigger runs the batch once, when it is full or its delay has expired.
+func (p *batch) trigger() {
+	p.start.Do(p.run)
+}
+
+func (p *batch) run() {
+	b := &p.env.batches
+	b.mu.Lock()
+	p.timer.Stop()
+	// Make sure no new calls are added to this batch.
+	if b.pending == p {
+		b.pending = nil
+	}
+	b.mu.Unlock()
+
+	var ok []bool
+	err := p.env.Update(func(txn *Txn) error {
+		ok = make([]bool, len(p.calls))
+		for i, c := range p.calls {
+			err := txn.Sub(func(sub *Txn) error {
+				return safelyCall(c.fn, sub)
+			})
+			ok[i] = err == nil
+		}
+		return nil
+	})
+	if err != nil {
+		// Nothing was committed, every call is retried on its own.
+		ok = make([]bool, len(p.calls))
+	}
+	for i, c := range p.calls {
+		if ok[i] {
+			c.err <- nil
+		}
+	}
+	for i, c := range p.calls {
+		if !ok[i] {
+			c.err <- p.env.Update(func(txn *Txn) error {
+				return safelyCall(c.fn, txn)
+			})
+		}
+	}
+}
+
+// panicked is the error returned by Batch when fn panics.
+type panicked struct {
+	reason interface{}
+}
+
+func (p panicked) Error() string {
+	if err, ok := p.reason.(error); ok {
+		return err.Error()
+	}
+	return fmt.Sprintf("panic: %v", p.reason)
+}
+
+func safelyCall(fn TxnOp, txn *Txn) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = panicked{p}
+		}
+	}()
+	return fn(txn)
+}
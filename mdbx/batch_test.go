@@ -0,0 +1,123 @@
+package mdbx
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEnv_Batch(t *testing.T) {
+	env, _, teardown := setup(t)
+	defer teardown()
+	env.SetMaxBatchDelay(50 * time.Millisecond)
+
+	var dbi DBI
+	err := env.Update(func(txn *Txn) (err error) {
+		dbi, err = txn.OpenRoot(0)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := env.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	txnid := info.RecentTxnID
+
+	const n = 50
+	errFail := errors.New("fail")
+	var failCalls int32
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = env.Batch(func(txn *Txn) error {
+				if err := txn.Put(dbi, []byte(fmt.Sprintf("k%02d", i)), []byte("v"), 0); err != nil {
+					return err
+				}
+				switch i {
+				case 7:
+					atomic.AddInt32(&failCalls, 1)
+					return errFail
+				case 9:
+					panic("oops")
+				}
+				return nil
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		switch i {
+		case 7:
+			if err != errFail || IsPanic(err) {
+				t.Errorf("call %d: unexpected error: %v", i, err)
+			}
+		case 9:
+			if !IsPanic(err) {
+				t.Errorf("call %d: unexpected error: %v", i, err)
+			}
+		default:
+			if err != nil {
+				t.Errorf("call %d: %v", i, err)
+			}
+		}
+	}
+	if failCalls != 2 {
+		t.Errorf("failing call was made %d times (!= 2)", failCalls)
+	}
+
+	err = env.View(func(txn *Txn) error {
+		for i := 0; i < n; i++ {
+			_, err := txn.Get(dbi, []byte(fmt.Sprintf("k%02d", i)))
+			if (i == 7 || i == 9) != IsNotFound(err) {
+				t.Errorf("key %d: unexpected error: %v", i, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err = env.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commits := info.RecentTxnID - txnid; commits >= n/2 {
+		t.Errorf("%d calls were committed in %d transactions", n, commits)
+	}
+}
+
+func TestEnv_Batch_MaxSize(t *testing.T) {
+	env, _, teardown := setup(t)
+	defer teardown()
+	env.SetMaxBatchSize(3)
+	env.SetMaxBatchDelay(time.Hour)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := env.Batch(func(txn *Txn) error {
+				dbi, err := txn.OpenRoot(0)
+				if err != nil {
+					return err
+				}
+				return txn.Put(dbi, []byte{byte(i)}, nil, 0)
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
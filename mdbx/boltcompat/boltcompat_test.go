@@ -0,0 +1,555 @@
+package boltcompat
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// openDB opens a database in a temporary directory, both of which are removed
+// when the test ends.
+func openDB(t *testing.T) *DB {
+	dir, err := ioutil.TempDir("", "boltcompat_test")
+	if err != nil {
+		t.Fatalf("Cannot create temporary directory: %s", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	db, err := Open(filepath.Join(dir, "bolt.db"), 0600, &Options{MaxSize: 64 << 20})
+	if err != nil {
+		t.Fatalf("Cannot open database: %s", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// model is a reference implementation of the semantics of a bbolt bucket.
+type model struct {
+	vals    map[string]string
+	buckets map[string]*model
+	seq     uint64
+}
+
+func newModel() *model {
+	return &model{vals: map[string]string{}, buckets: map[string]*model{}}
+}
+
+type item struct {
+	k, v   string
+	bucket bool
+}
+
+// items returns the keys and nested buckets of m in bbolt's order.
+func (m *model) items() []item {
+	var items []item
+	for k, v := range m.vals {
+		items = append(items, item{k: k, v: v})
+	}
+	for k := range m.buckets {
+		items = append(items, item{k: k, bucket: true})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].k < items[j].k })
+	return items
+}
+
+// randKey returns short keys with the bytes used by the key layout of the
+// package, so that collisions between nested buckets would show.
+func randKey(rng *rand.Rand) []byte {
+	alphabet := []byte{0x00, 0x01, 0x02, 'a', 'b', 0xff}
+	k := make([]byte, 1+rng.Intn(3))
+	for i := range k {
+		k[i] = alphabet[rng.Intn(len(alphabet))]
+	}
+	return k
+}
+
+func sameErr(t *testing.T, op string, got, want error) {
+	t.Helper()
+	if got != want {
+		t.Fatalf("%s: error %v, want %v", op, got, want)
+	}
+}
+
+// step applies a random operation to both the bucket b and the model m,
+// recursing into nested buckets.
+func step(t *testing.T, rng *rand.Rand, b *Bucket, m *model, depth int) {
+	t.Helper()
+	k := randKey(rng)
+	sk := string(k)
+	switch op := rng.Intn(10); {
+	case op < 4:
+		v := make([]byte, rng.Intn(4))
+		rng.Read(v)
+		var want error
+		if _, ok := m.buckets[sk]; ok {
+			want = ErrIncompatibleValue
+		} else {
+			m.vals[sk] = string(v)
+		}
+		sameErr(t, "Put", b.Put(k, v), want)
+	case op < 6:
+		var want error
+		if _, ok := m.buckets[sk]; ok {
+			want = ErrIncompatibleValue
+		} else {
+			delete(m.vals, sk)
+		}
+		sameErr(t, "Delete", b.Delete(k), want)
+	case op < 7:
+		var want error
+		if _, ok := m.vals[sk]; ok {
+			want = ErrIncompatibleValue
+		} else if _, ok := m.buckets[sk]; !ok {
+			m.buckets[sk] = newModel()
+		}
+		_, err := b.CreateBucketIfNotExists(k)
+		sameErr(t, "CreateBucketIfNotExists", err, want)
+	case op < 8:
+		var want error
+		if _, ok := m.vals[sk]; ok {
+			want = ErrIncompatibleValue
+		} else if _, ok := m.buckets[sk]; !ok {
+			want = ErrBucketNotFound
+		} else {
+			delete(m.buckets, sk)
+		}
+		sameErr(t, "DeleteBucket", b.DeleteBucket(k), want)
+	case op < 9:
+		m.seq++
+		seq, err := b.NextSequence()
+		sameErr(t, "NextSequence", err, nil)
+		if seq != m.seq {
+			t.Fatalf("NextSequence: %d, want %d", seq, m.seq)
+		}
+	default:
+		if depth >= 3 || len(m.buckets) == 0 {
+			return
+		}
+		for name, child := range m.buckets {
+			nested := b.Bucket([]byte(name))
+			if nested == nil {
+				t.Fatalf("Bucket(%q) = nil", name)
+			}
+			step(t, rng, nested, child, depth+1)
+			return
+		}
+	}
+}
+
+// check compares the content of b to the model m recursively.
+func check(t *testing.T, rng *rand.Rand, b *Bucket, m *model) {
+	t.Helper()
+	items := m.items()
+
+	var got []item
+	err := b.ForEach(func(k, v []byte) error {
+		got = append(got, item{k: string(k), v: string(v), bucket: v == nil})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(items) {
+		t.Fatalf("ForEach: %d items, want %d", len(got), len(items))
+	}
+	for i := range items {
+		if got[i] != items[i] {
+			t.Fatalf("ForEach: item %d = %+v, want %+v", i, got[i], items[i])
+		}
+	}
+
+	c := b.Cursor()
+	i := 0
+	for k, v := c.First(); k != nil; k, v = c.Next() {
+		if i >= len(items) || string(k) != items[i].k || (v == nil) != items[i].bucket || string(v) != items[i].v {
+			t.Fatalf("Next: item %d = %q, %q", i, k, v)
+		}
+		i++
+	}
+	if i != len(items) {
+		t.Fatalf("Next: %d items, want %d", i, len(items))
+	}
+	i = len(items)
+	for k, _ := c.Last(); k != nil; k, _ = c.Prev() {
+		i--
+		if i < 0 || string(k) != items[i].k {
+			t.Fatalf("Prev: item %d = %q", i, k)
+		}
+	}
+	if i != 0 {
+		t.Fatalf("Prev: %d items missing", i)
+	}
+	for n := 0; n < 8; n++ {
+		seek := randKey(rng)
+		j := sort.Search(len(items), func(j int) bool { return items[j].k >= string(seek) })
+		k, _ := c.Seek(seek)
+		if j == len(items) {
+			if k != nil {
+				t.Fatalf("Seek(%q) = %q, want nil", seek, k)
+			}
+		} else if string(k) != items[j].k {
+			t.Fatalf("Seek(%q) = %q, want %q", seek, k, items[j].k)
+		}
+	}
+
+	for k, v := range m.vals {
+		if got := b.Get([]byte(k)); got == nil || string(got) != v {
+			t.Fatalf("Get(%q) = %q, want %q", k, got, v)
+		}
+	}
+	if seq := b.Sequence(); seq != m.seq {
+		t.Fatalf("Sequence: %d, want %d", seq, m.seq)
+	}
+	for name, child := range m.buckets {
+		if b.Get([]byte(name)) != nil {
+			t.Fatalf("Get(%q) of a bucket is not nil", name)
+		}
+		nested := b.Bucket([]byte(name))
+		if nested == nil {
+			t.Fatalf("Bucket(%q) = nil", name)
+		}
+		check(t, rng, nested, child)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	db := openDB(t)
+
+	rng := rand.New(rand.NewSource(1))
+	names := []string{"a", "b", "c"}
+	models := map[string]*model{}
+	for round := 0; round < 100; round++ {
+		err := db.Update(func(tx *Tx) error {
+			for n := 0; n < 20; n++ {
+				name := names[rng.Intn(len(names))]
+				if rng.Intn(50) == 0 {
+					var want error
+					if models[name] == nil {
+						want = ErrBucketNotFound
+					}
+					sameErr(t, "Tx.DeleteBucket", tx.DeleteBucket([]byte(name)), want)
+					delete(models, name)
+					continue
+				}
+				b, err := tx.CreateBucketIfNotExists([]byte(name))
+				if err != nil {
+					return err
+				}
+				if models[name] == nil {
+					models[name] = newModel()
+				}
+				step(t, rng, b, models[name], 0)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = db.View(func(tx *Tx) error {
+			var got []string
+			err := tx.ForEach(func(name []byte, b *Bucket) error {
+				got = append(got, string(name))
+				check(t, rng, b, models[string(name)])
+				return nil
+			})
+			if len(got) != len(models) {
+				t.Fatalf("Tx.ForEach: buckets %q, want %d", got, len(models))
+			}
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBucket_Errors(t *testing.T) {
+	db := openDB(t)
+
+	err := db.Update(func(tx *Tx) error {
+		if _, err := tx.CreateBucket(nil); err != ErrBucketNameRequired {
+			t.Errorf("CreateBucket(nil): %v", err)
+		}
+		if _, err := tx.CreateBucket([]byte("a\x00b")); err != ErrBucketNameInvalid {
+			t.Errorf("CreateBucket(null byte): %v", err)
+		}
+		if err := tx.DeleteBucket([]byte("missing")); err != ErrBucketNotFound {
+			t.Errorf("DeleteBucket(missing): %v", err)
+		}
+		b, err := tx.CreateBucket([]byte("widgets"))
+		if err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucket([]byte("widgets")); err != ErrBucketExists {
+			t.Errorf("CreateBucket(widgets): %v", err)
+		}
+		if err := b.Put(nil, []byte("bar")); err != ErrKeyRequired {
+			t.Errorf("Put(nil): %v", err)
+		}
+		if err := b.Put(make([]byte, 64<<10), []byte("bar")); err != ErrKeyTooLarge {
+			t.Errorf("Put(large key): %v", err)
+		}
+		if err := b.Put([]byte("foo"), []byte("bar")); err != nil {
+			return err
+		}
+		if _, err := b.CreateBucket([]byte("foo")); err != ErrIncompatibleValue {
+			t.Errorf("CreateBucket(foo): %v", err)
+		}
+		if err := b.DeleteBucket([]byte("foo")); err != ErrIncompatibleValue {
+			t.Errorf("DeleteBucket(foo): %v", err)
+		}
+		if _, err := b.CreateBucket([]byte("sub")); err != nil {
+			return err
+		}
+		if _, err := b.CreateBucket([]byte("sub")); err != ErrBucketExists {
+			t.Errorf("CreateBucket(sub): %v", err)
+		}
+		if err := b.Put([]byte("sub"), []byte("bar")); err != ErrIncompatibleValue {
+			t.Errorf("Put(sub): %v", err)
+		}
+		if err := b.Delete([]byte("sub")); err != ErrIncompatibleValue {
+			t.Errorf("Delete(sub): %v", err)
+		}
+		if err := b.Delete([]byte("missing")); err != nil {
+			t.Errorf("Delete(missing): %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.View(func(tx *Tx) error {
+		if _, err := tx.CreateBucket([]byte("x")); err != ErrTxNotWritable {
+			t.Errorf("CreateBucket: %v", err)
+		}
+		b := tx.Bucket([]byte("widgets"))
+		if b == nil {
+			t.Fatal("bucket widgets not found")
+		}
+		if err := b.Put([]byte("foo"), nil); err != ErrTxNotWritable {
+			t.Errorf("Put: %v", err)
+		}
+		if err := b.Delete([]byte("foo")); err != ErrTxNotWritable {
+			t.Errorf("Delete: %v", err)
+		}
+		if _, err := b.NextSequence(); err != ErrTxNotWritable {
+			t.Errorf("NextSequence: %v", err)
+		}
+		if err := b.Cursor().Delete(); err != ErrTxNotWritable {
+			t.Errorf("Cursor.Delete: %v", err)
+		}
+		if tx.Bucket([]byte("missing")) != nil {
+			t.Error("bucket missing found")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCursor_Delete(t *testing.T) {
+	db := openDB(t)
+
+	err := db.Update(func(tx *Tx) error {
+		b, err := tx.CreateBucket([]byte("widgets"))
+		if err != nil {
+			return err
+		}
+		for _, k := range []string{"a", "b", "c", "d"} {
+			if err := b.Put([]byte(k), []byte(k)); err != nil {
+				return err
+			}
+		}
+		if _, err := b.CreateBucket([]byte("e")); err != nil {
+			return err
+		}
+
+		c := b.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if v == nil {
+				if err := c.Delete(); err != ErrIncompatibleValue {
+					t.Errorf("Delete(%q): %v", k, err)
+				}
+				continue
+			}
+			if string(k) == "b" || string(k) == "c" {
+				if err := c.Delete(); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.View(func(tx *Tx) error {
+		var keys []string
+		err := tx.Bucket([]byte("widgets")).ForEach(func(k, v []byte) error {
+			keys = append(keys, string(k))
+			return nil
+		})
+		if got := strings.Join(keys, ","); got != "a,d,e" {
+			t.Errorf("keys %s", got)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDB_Update_Rollback(t *testing.T) {
+	db := openDB(t)
+
+	errAbort := errors.New("abort")
+	err := db.Update(func(tx *Tx) error {
+		b, err := tx.CreateBucket([]byte("widgets"))
+		if err != nil {
+			return err
+		}
+		if err := b.Put([]byte("foo"), []byte("bar")); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if err != errAbort {
+		t.Fatalf("Update: %v", err)
+	}
+	err = db.View(func(tx *Tx) error {
+		if tx.Bucket([]byte("widgets")) != nil {
+			t.Error("bucket created by a rolled back transaction")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDB_Batch(t *testing.T) {
+	db := openDB(t)
+
+	err := db.Update(func(tx *Tx) error {
+		_, err := tx.CreateBucket([]byte("widgets"))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- db.Batch(func(tx *Tx) error {
+				return tx.Bucket([]byte("widgets")).Put([]byte{byte(i)}, []byte{byte(i)})
+			})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = db.View(func(tx *Tx) error {
+		b := tx.Bucket([]byte("widgets"))
+		for i := 0; i < n; i++ {
+			if v := b.Get([]byte{byte(i)}); !bytes.Equal(v, []byte{byte(i)}) {
+				t.Errorf("Get(%d) = %x", i, v)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDB_Batch_Limits(t *testing.T) {
+	db := openDB(t)
+
+	err := db.Update(func(tx *Tx) error {
+		_, err := tx.CreateBucket([]byte("widgets"))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lastTxnID := func() int64 {
+		info, err := db.Env().Info()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return info.RecentTxnID
+	}
+
+	// commits runs two concurrent calls to Batch storing val and returns the
+	// number of transactions they were committed in.
+	commits := func(val byte) int64 {
+		before := lastTxnID()
+		errc := make(chan error, 2)
+		for i := 0; i < 2; i++ {
+			go func(i int) {
+				errc <- db.Batch(func(tx *Tx) error {
+					return tx.Bucket([]byte("widgets")).Put([]byte{byte(i)}, []byte{val})
+				})
+			}(i)
+		}
+		timeout := time.After(10 * time.Second)
+		for i := 0; i < 2; i++ {
+			select {
+			case err := <-errc:
+				if err != nil {
+					t.Fatal(err)
+				}
+			case <-timeout:
+				t.Fatal("batch waited for its delay")
+			}
+		}
+		return lastTxnID() - before
+	}
+
+	// limits set on the environment are kept while the fields are unchanged
+	db.Env().SetMaxBatchSize(1)
+	db.Env().SetMaxBatchDelay(time.Hour)
+	if n := commits(1); n != 2 {
+		t.Errorf("%d commits with limits set on the environment, want 2", n)
+	}
+
+	db.MaxBatchSize = 2
+	if n := commits(2); n != 1 {
+		t.Errorf("%d commits after setting MaxBatchSize, want 1", n)
+	}
+}
+
+func TestDB_Closed(t *testing.T) {
+	db := openDB(t)
+
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.View(func(*Tx) error { return nil }); err != ErrDatabaseNotOpen {
+		t.Errorf("View: %v", err)
+	}
+	if err := db.Close(); err != ErrDatabaseNotOpen {
+		t.Errorf("Close: %v", err)
+	}
+}
@@ -0,0 +1,277 @@
+package boltcompat
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/xzfkiller/mdbx-go/mdbx"
+)
+
+// The items of a bucket are stored in the database of its top-level bucket
+// under the prefix of the bucket, which is empty for a top-level bucket:
+//
+//	prefix 0x00                 the sequence of the bucket
+//	prefix 0x01 key             a key of the bucket, or the name of a nested bucket
+//	prefix 0x02 name 0x00 0x01  the prefix of the nested bucket name
+//
+// Null bytes of nested bucket names are escaped as 0x00 0xff, so that
+// prefixes are unambiguous and sort nested buckets in name order.  Values of
+// keys are tagged with their kind, a nested bucket is marked by a single
+// tagBucket byte.
+const (
+	spaceSequence = 0x00
+	spaceKeys     = 0x01
+	spaceBuckets  = 0x02
+
+	tagValue  = 0x00
+	tagBucket = 0x01
+)
+
+// Bucket represents a collection of key/value pairs inside the database.
+type Bucket struct {
+	tx     *Tx
+	dbi    mdbx.DBI
+	prefix []byte
+}
+
+func (b *Bucket) join(space byte, name []byte) []byte {
+	k := make([]byte, 0, len(b.prefix)+1+len(name))
+	k = append(k, b.prefix...)
+	k = append(k, space)
+	return append(k, name...)
+}
+
+func (b *Bucket) keysPrefix() []byte {
+	return b.join(spaceKeys, nil)
+}
+
+func (b *Bucket) nested(name []byte) *Bucket {
+	p := b.join(spaceBuckets, nil)
+	for _, c := range name {
+		p = append(p, c)
+		if c == 0 {
+			p = append(p, 0xff)
+		}
+	}
+	p = append(p, 0x00, 0x01)
+	return &Bucket{tx: b.tx, dbi: b.dbi, prefix: p}
+}
+
+// get returns the tagged value of key, nil if key is not found.
+func (b *Bucket) get(key []byte) ([]byte, error) {
+	v, err := b.tx.txn.Get(b.dbi, b.join(spaceKeys, key))
+	if mdbx.IsNotFound(err) {
+		return nil, nil
+	}
+	return v, keyError(err)
+}
+
+// keyError translates the MDBX error for an oversized key.
+func keyError(err error) error {
+	if mdbx.IsErrno(err, mdbx.BadValSize) {
+		return ErrKeyTooLarge
+	}
+	return err
+}
+
+// Tx returns the tx of the bucket.
+func (b *Bucket) Tx() *Tx {
+	return b.tx
+}
+
+// Writable returns whether the bucket is writable.
+func (b *Bucket) Writable() bool {
+	return b.tx.writable
+}
+
+// Cursor creates a cursor associated with the bucket.  The cursor is only
+// valid as long as the transaction is open.
+func (b *Bucket) Cursor() *Cursor {
+	return &Cursor{bucket: b, lo: b.keysPrefix()}
+}
+
+// Bucket retrieves a nested bucket by name.  Returns nil if the bucket does
+// not exist.
+func (b *Bucket) Bucket(name []byte) *Bucket {
+	v, err := b.get(name)
+	if err != nil || len(v) == 0 || v[0] != tagBucket {
+		return nil
+	}
+	return b.nested(name)
+}
+
+// CreateBucket creates a new nested bucket at the given key and returns it.
+// Returns an error if the key already exists, if the bucket name is blank, or
+// if the bucket name is too long.
+func (b *Bucket) CreateBucket(key []byte) (*Bucket, error) {
+	if !b.tx.writable {
+		return nil, ErrTxNotWritable
+	}
+	if len(key) == 0 {
+		return nil, ErrBucketNameRequired
+	}
+	v, err := b.get(key)
+	if err != nil {
+		return nil, err
+	}
+	if v != nil {
+		if v[0] == tagBucket {
+			return nil, ErrBucketExists
+		}
+		return nil, ErrIncompatibleValue
+	}
+	if err = b.put(key, []byte{tagBucket}); err != nil {
+		return nil, err
+	}
+	return b.nested(key), nil
+}
+
+// CreateBucketIfNotExists creates a new nested bucket if it doesn't already
+// exist and returns a reference to it.
+func (b *Bucket) CreateBucketIfNotExists(key []byte) (*Bucket, error) {
+	child, err := b.CreateBucket(key)
+	if err == ErrBucketExists {
+		return b.nested(key), nil
+	}
+	return child, err
+}
+
+// DeleteBucket deletes a nested bucket at the given key, and all of its
+// content.  Returns an error if the bucket does not exist, or if the key
+// represents a non-bucket value.
+func (b *Bucket) DeleteBucket(key []byte) error {
+	if !b.tx.writable {
+		return ErrTxNotWritable
+	}
+	v, err := b.get(key)
+	if err != nil {
+		return err
+	}
+	if v == nil {
+		return ErrBucketNotFound
+	}
+	if v[0] != tagBucket {
+		return ErrIncompatibleValue
+	}
+
+	cur, err := b.tx.txn.OpenCursor(b.dbi)
+	if err != nil {
+		return err
+	}
+	defer cur.Close()
+	prefix := b.nested(key).prefix
+	for {
+		k, _, err := cur.Get(prefix, nil, mdbx.SetRange)
+		if mdbx.IsNotFound(err) || err == nil && !bytes.HasPrefix(k, prefix) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err = cur.Del(0); err != nil {
+			return err
+		}
+	}
+	return b.tx.txn.Del(b.dbi, b.join(spaceKeys, key), nil)
+}
+
+// Get retrieves the value for a key in the bucket.  Returns a nil value if the
+// key does not exist or if the key is a nested bucket.  The returned value is
+// only valid for the life of the transaction.
+func (b *Bucket) Get(key []byte) []byte {
+	v, err := b.get(key)
+	if err != nil || len(v) == 0 || v[0] != tagValue {
+		return nil
+	}
+	return v[1:]
+}
+
+// Put sets the value for a key in the bucket.  If the key exist then its
+// previous value will be overwritten.  Returns an error if the bucket was
+// created from a read-only transaction, if the key is blank, or if the key is
+// too large.
+func (b *Bucket) Put(key []byte, value []byte) error {
+	if !b.tx.writable {
+		return ErrTxNotWritable
+	}
+	if len(key) == 0 {
+		return ErrKeyRequired
+	}
+	v, err := b.get(key)
+	if err != nil {
+		return err
+	}
+	if v != nil && v[0] == tagBucket {
+		return ErrIncompatibleValue
+	}
+	tagged := make([]byte, 1+len(value))
+	tagged[0] = tagValue
+	copy(tagged[1:], value)
+	return b.put(key, tagged)
+}
+
+func (b *Bucket) put(key, tagged []byte) error {
+	return keyError(b.tx.txn.Put(b.dbi, b.join(spaceKeys, key), tagged, 0))
+}
+
+// Delete removes a key from the bucket.  If the key does not exist then
+// nothing is done and a nil error is returned.  Returns an error if the bucket
+// was created from a read-only transaction or if the key is a nested bucket.
+func (b *Bucket) Delete(key []byte) error {
+	if !b.tx.writable {
+		return ErrTxNotWritable
+	}
+	v, err := b.get(key)
+	if err != nil || v == nil {
+		return err
+	}
+	if v[0] == tagBucket {
+		return ErrIncompatibleValue
+	}
+	return b.tx.txn.Del(b.dbi, b.join(spaceKeys, key), nil)
+}
+
+// Sequence returns the current integer for the bucket without incrementing
+// it.
+func (b *Bucket) Sequence() uint64 {
+	v, err := b.tx.txn.Get(b.dbi, b.join(spaceSequence, nil))
+	if err != nil || len(v) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(v)
+}
+
+// SetSequence updates the sequence number for the bucket.
+func (b *Bucket) SetSequence(v uint64) error {
+	if !b.tx.writable {
+		return ErrTxNotWritable
+	}
+	seq := make([]byte, 8)
+	binary.BigEndian.PutUint64(seq, v)
+	return b.tx.txn.Put(b.dbi, b.join(spaceSequence, nil), seq, 0)
+}
+
+// NextSequence returns an autoincrementing integer for the bucket.
+func (b *Bucket) NextSequence() (uint64, error) {
+	if !b.tx.writable {
+		return 0, ErrTxNotWritable
+	}
+	seq := b.Sequence() + 1
+	return seq, b.SetSequence(seq)
+}
+
+// ForEach executes a function for each key/value pair in a bucket, in order.
+// Nested buckets are reported with a nil value.  If the provided function
+// returns an error then the iteration is stopped and the error is returned to
+// the caller.  The provided function must not modify the bucket; this will
+// result in undefined behavior.
+func (b *Bucket) ForEach(fn func(k, v []byte) error) error {
+	c := &Cursor{bucket: b, lo: b.keysPrefix(), owned: true}
+	defer c.close()
+	for k, v := c.First(); k != nil; k, v = c.Next() {
+		if err := fn(k, v); err != nil {
+			return err
+		}
+	}
+	return c.err
+}
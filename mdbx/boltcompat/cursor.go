@@ -0,0 +1,144 @@
+package boltcompat
+
+import (
+	"bytes"
+
+	"github.com/xzfkiller/mdbx-go/mdbx"
+)
+
+// Cursor represents an iterator that can traverse over all key/value pairs in
+// a bucket in sorted order.  Nested buckets are returned with a nil value.
+// Cursors are only valid for the life of the transaction and are closed with
+// it.
+type Cursor struct {
+	bucket *Bucket
+	lo     []byte // prefix of the keys of the bucket
+	cur    *mdbx.Cursor
+	err    error
+	owned  bool // cur is closed by close rather than by the Tx
+}
+
+// Bucket returns the bucket that this cursor was created from.
+func (c *Cursor) Bucket() *Bucket {
+	return c.bucket
+}
+
+func (c *Cursor) open() bool {
+	if c.cur != nil {
+		return true
+	}
+	if c.err != nil {
+		return false
+	}
+	if c.owned {
+		c.cur, c.err = c.bucket.tx.txn.OpenCursor(c.bucket.dbi)
+	} else {
+		c.cur, c.err = c.bucket.tx.openCursor(c.bucket.dbi)
+	}
+	return c.err == nil
+}
+
+func (c *Cursor) close() {
+	if c.owned && c.cur != nil {
+		c.cur.Close()
+		c.cur = nil
+	}
+}
+
+// item converts the result of a cursor operation to a key and value of the
+// bucket, both nil if the cursor left the bucket.
+func (c *Cursor) item(k, v []byte, err error) ([]byte, []byte) {
+	if err != nil {
+		if !mdbx.IsNotFound(err) {
+			c.err = err
+		}
+		return nil, nil
+	}
+	if !bytes.HasPrefix(k, c.lo) || len(v) == 0 {
+		return nil, nil
+	}
+	k = k[len(c.lo):]
+	if v[0] == tagBucket {
+		return k, nil
+	}
+	return k, v[1:]
+}
+
+func (c *Cursor) seek(key []byte) ([]byte, []byte) {
+	if !c.open() {
+		return nil, nil
+	}
+	return c.item(c.cur.Get(key, nil, mdbx.SetRange))
+}
+
+// First moves the cursor to the first item in the bucket and returns its key
+// and value.  If the bucket is empty then a nil key and value are returned.
+func (c *Cursor) First() (key []byte, value []byte) {
+	return c.seek(c.lo)
+}
+
+// Last moves the cursor to the last item in the bucket and returns its key and
+// value.  If the bucket is empty then a nil key and value are returned.
+func (c *Cursor) Last() (key []byte, value []byte) {
+	if !c.open() {
+		return nil, nil
+	}
+	hi := append(c.lo[:len(c.lo)-1:len(c.lo)-1], c.lo[len(c.lo)-1]+1)
+	_, _, err := c.cur.Get(hi, nil, mdbx.SetRange)
+	if mdbx.IsNotFound(err) {
+		return c.item(c.cur.Get(nil, nil, mdbx.Last))
+	}
+	if err != nil {
+		c.err = err
+		return nil, nil
+	}
+	return c.item(c.cur.Get(nil, nil, mdbx.Prev))
+}
+
+// Next moves the cursor to the next item in the bucket and returns its key and
+// value.  If the cursor is at the end of the bucket then a nil key and value
+// are returned.
+func (c *Cursor) Next() (key []byte, value []byte) {
+	if !c.open() {
+		return nil, nil
+	}
+	return c.item(c.cur.Get(nil, nil, mdbx.Next))
+}
+
+// Prev moves the cursor to the previous item in the bucket and returns its key
+// and value.  If the cursor is at the beginning of the bucket then a nil key
+// and value are returned.
+func (c *Cursor) Prev() (key []byte, value []byte) {
+	if !c.open() {
+		return nil, nil
+	}
+	return c.item(c.cur.Get(nil, nil, mdbx.Prev))
+}
+
+// Seek moves the cursor to a given key and returns it.  If the key does not
+// exist then the next key is used.  If no keys follow, a nil key is returned.
+func (c *Cursor) Seek(seek []byte) (key []byte, value []byte) {
+	k := make([]byte, 0, len(c.lo)+len(seek))
+	k = append(k, c.lo...)
+	return c.seek(append(k, seek...))
+}
+
+// Delete removes the current key/value under the cursor from the bucket.
+// Delete fails if current key/value is a bucket or if the transaction is not
+// writable.
+func (c *Cursor) Delete() error {
+	if !c.bucket.tx.writable {
+		return ErrTxNotWritable
+	}
+	if !c.open() {
+		return c.err
+	}
+	_, v, err := c.cur.Get(nil, nil, mdbx.GetCurrent)
+	if err != nil {
+		return err
+	}
+	if len(v) > 0 && v[0] == tagBucket {
+		return ErrIncompatibleValue
+	}
+	return c.cur.Del(0)
+}
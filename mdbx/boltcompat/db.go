@@ -0,0 +1,242 @@
+/*
+Package boltcompat provides a subset of the API of go.etcd.io/bbolt on top of
+an MDBX environment, so that applications may be migrated by changing an
+import.
+
+The package provides Open, DB.View, DB.Update and DB.Batch,
+Tx.Bucket/CreateBucket/CreateBucketIfNotExists/DeleteBucket/ForEach,
+Bucket.Get/Put/Delete/ForEach/NextSequence/Cursor and nested buckets, and
+Cursor.First/Last/Next/Prev/Seek/Delete.  Manually managed transactions
+(DB.Begin) are not provided.
+
+Top-level buckets are stored as named MDBX databases, so their names cannot
+contain null bytes and their number is limited by Options.MaxBuckets.  Nested
+buckets are stored in the database of their top-level bucket under a key
+prefix, which takes a few bytes from the maximum key size.  Databases written
+by this package are not readable by bbolt and the other way around.
+
+As with bbolt, slices returned by Get and cursors point into the memory map
+and are only valid for the life of the transaction.
+*/
+package boltcompat
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/xzfkiller/mdbx-go/mdbx"
+)
+
+// DefaultMaxBuckets is the number of top-level buckets allowed when
+// Options.MaxBuckets is zero.
+const DefaultMaxBuckets = 256
+
+// DefaultMaxSize is the upper bound of the database size when Options.MaxSize
+// is zero.  Address space is reserved for the whole size, the file grows on
+// demand.
+var DefaultMaxSize = defaultMaxSize()
+
+func defaultMaxSize() int {
+	if strconv.IntSize == 64 {
+		return 64 << 30
+	}
+	return 1 << 30
+}
+
+// Options represents the options that can be set when opening a database.
+// The fields of bbolt's Options which have no MDBX equivalent are accepted and
+// ignored.
+type Options struct {
+	// Timeout is ignored, MDBX does not lock the database file exclusively.
+	Timeout time.Duration
+
+	// NoGrowSync is ignored.
+	NoGrowSync bool
+
+	// NoFreelistSync is ignored.
+	NoFreelistSync bool
+
+	// ReadOnly opens the database in read-only mode.
+	ReadOnly bool
+
+	// MmapFlags is ignored.
+	MmapFlags int
+
+	// InitialMmapSize is the initial size of the database file.
+	InitialMmapSize int
+
+	// PageSize overrides the default OS page size for a new database.
+	PageSize int
+
+	// NoSync sets the initial value of DB.NoSync.
+	NoSync bool
+
+	// MaxBuckets is the maximum number of top-level buckets.  If zero
+	// DefaultMaxBuckets is used.
+	MaxBuckets int
+
+	// MaxSize is the upper bound of the database size.  If zero
+	// DefaultMaxSize is used.
+	MaxSize int
+}
+
+// DB represents a collection of buckets persisted to a file on disk.
+type DB struct {
+	// NoSync makes commits skip the fsync, see mdbx.UpdateOptions.
+	NoSync bool
+
+	// MaxBatchSize is the maximum size of a batch.  Zero uses
+	// mdbx.DefaultMaxBatchSize.  The limits of batches are settings of the
+	// environment, a change is applied to it by the next call to Batch.
+	MaxBatchSize int
+
+	// MaxBatchDelay is the maximum delay before a batch starts.  Zero uses
+	// mdbx.DefaultMaxBatchDelay.
+	MaxBatchDelay time.Duration
+
+	env      *mdbx.Env
+	path     string
+	readOnly bool
+
+	batchMu    sync.Mutex
+	batchSize  int           // MaxBatchSize last applied to env
+	batchDelay time.Duration // MaxBatchDelay last applied to env
+}
+
+// Open creates and opens a database at the given path.  If the file does not
+// exist it is created with the given mode.  Passing nil options uses the
+// defaults.
+func Open(path string, mode os.FileMode, options *Options) (*DB, error) {
+	var opts Options
+	if options != nil {
+		opts = *options
+	}
+	if opts.MaxBuckets == 0 {
+		opts.MaxBuckets = DefaultMaxBuckets
+	}
+	if opts.MaxSize == 0 {
+		opts.MaxSize = DefaultMaxSize
+	}
+	if opts.PageSize == 0 {
+		opts.PageSize = os.Getpagesize()
+	}
+	size := -1
+	if opts.InitialMmapSize > 0 {
+		size = opts.InitialMmapSize
+	}
+
+	env, err := mdbx.NewEnv()
+	if err != nil {
+		return nil, err
+	}
+	if err = env.SetMaxDBs(opts.MaxBuckets); err != nil {
+		env.Close()
+		return nil, err
+	}
+	if err = env.SetGeometry(-1, size, opts.MaxSize, -1, -1, opts.PageSize); err != nil {
+		env.Close()
+		return nil, err
+	}
+	var flags uint
+	if opts.ReadOnly {
+		flags |= mdbx.Readonly
+	}
+	err = env.OpenWithOptions(path, mdbx.OpenOptions{Flags: flags, Mode: mode})
+	if err != nil {
+		env.Close()
+		return nil, err
+	}
+	return &DB{
+		NoSync:   opts.NoSync,
+		env:      env,
+		path:     path,
+		readOnly: opts.ReadOnly,
+	}, nil
+}
+
+// Path returns the path to the currently open database file.
+func (db *DB) Path() string {
+	return db.path
+}
+
+// IsReadOnly returns true if the database was opened read-only.
+func (db *DB) IsReadOnly() bool {
+	return db.readOnly
+}
+
+// Env returns the underlying MDBX environment.
+func (db *DB) Env() *mdbx.Env {
+	return db.env
+}
+
+// Close releases all database resources.  All transactions must be closed
+// before closing the database.
+func (db *DB) Close() error {
+	if db.env == nil {
+		return ErrDatabaseNotOpen
+	}
+	err := db.env.Close()
+	db.env = nil
+	return err
+}
+
+// View executes a function within the context of a managed read-only
+// transaction.  Any error that is returned from the function is returned from
+// View.
+func (db *DB) View(fn func(*Tx) error) error {
+	if db.env == nil {
+		return ErrDatabaseNotOpen
+	}
+	return db.env.View(func(txn *mdbx.Txn) error {
+		return runTx(db, txn, false, fn)
+	})
+}
+
+// Update executes a function within the context of a read-write managed
+// transaction.  If no error is returned from the function then the
+// transaction is committed, otherwise it is rolled back.
+func (db *DB) Update(fn func(*Tx) error) error {
+	if db.env == nil {
+		return ErrDatabaseNotOpen
+	}
+	if db.readOnly {
+		return ErrDatabaseReadOnly
+	}
+	opts := mdbx.UpdateOptions{NoSync: db.NoSync}
+	return db.env.UpdateWithOptions(opts, func(txn *mdbx.Txn) error {
+		return runTx(db, txn, true, fn)
+	})
+}
+
+// Batch calls fn as part of a batch, see mdbx.Env.Batch.  As with bbolt fn
+// may be called more than once and must be idempotent.
+func (db *DB) Batch(fn func(*Tx) error) error {
+	if db.env == nil {
+		return ErrDatabaseNotOpen
+	}
+	if db.readOnly {
+		return ErrDatabaseReadOnly
+	}
+	db.applyBatchLimits()
+	return db.env.Batch(func(txn *mdbx.Txn) error {
+		return runTx(db, txn, true, fn)
+	})
+}
+
+// applyBatchLimits sets the batch limits of the environment to MaxBatchSize
+// and MaxBatchDelay if they were changed, and leaves them alone otherwise, so
+// that limits set directly on the environment are not overwritten.
+func (db *DB) applyBatchLimits() {
+	db.batchMu.Lock()
+	defer db.batchMu.Unlock()
+	if db.MaxBatchSize != db.batchSize {
+		db.env.SetMaxBatchSize(db.MaxBatchSize)
+		db.batchSize = db.MaxBatchSize
+	}
+	if db.MaxBatchDelay != db.batchDelay {
+		db.env.SetMaxBatchDelay(db.MaxBatchDelay)
+		db.batchDelay = db.MaxBatchDelay
+	}
+}
@@ -0,0 +1,50 @@
+package boltcompat
+
+import "errors"
+
+// These errors can be returned when opening or calling methods on a DB.  Their
+// messages match the errors of bbolt.
+var (
+	// ErrDatabaseNotOpen is returned when a DB instance is accessed before it
+	// is opened or after it is closed.
+	ErrDatabaseNotOpen = errors.New("database not open")
+
+	// ErrDatabaseReadOnly is returned when a mutating transaction is started
+	// on a read-only database.
+	ErrDatabaseReadOnly = errors.New("database is in read-only mode")
+)
+
+// These errors can occur when putting or deleting a value or a bucket.
+var (
+	// ErrTxNotWritable is returned when performing a write operation on a
+	// read-only transaction.
+	ErrTxNotWritable = errors.New("tx not writable")
+
+	// ErrBucketNotFound is returned when trying to access a bucket that has
+	// not been created yet.
+	ErrBucketNotFound = errors.New("bucket not found")
+
+	// ErrBucketExists is returned when creating a bucket that already exists.
+	ErrBucketExists = errors.New("bucket already exists")
+
+	// ErrBucketNameRequired is returned when creating a bucket with a blank
+	// name.
+	ErrBucketNameRequired = errors.New("bucket name required")
+
+	// ErrBucketNameInvalid is returned when creating a top-level bucket with a
+	// name containing a null byte, which MDBX does not allow in database
+	// names.  It has no bbolt equivalent.
+	ErrBucketNameInvalid = errors.New("bucket name contains a null byte")
+
+	// ErrKeyRequired is returned when inserting a zero-length key.
+	ErrKeyRequired = errors.New("key required")
+
+	// ErrKeyTooLarge is returned when inserting a key that is larger than
+	// MDBX allows.
+	ErrKeyTooLarge = errors.New("key too large")
+
+	// ErrIncompatibleValue is returned when trying to create or delete a
+	// bucket on an existing non-bucket key or when trying to create or delete
+	// a non-bucket key on an existing bucket key.
+	ErrIncompatibleValue = errors.New("incompatible value")
+)
@@ -0,0 +1,149 @@
+package boltcompat
+
+import (
+	"bytes"
+
+	"github.com/xzfkiller/mdbx-go/mdbx"
+)
+
+// Tx represents a read-only or read/write transaction on the database.  A Tx
+// is only valid inside the function passed to DB.View, DB.Update or DB.Batch.
+type Tx struct {
+	db       *DB
+	txn      *mdbx.Txn
+	writable bool
+	cursors  []*mdbx.Cursor
+}
+
+// runTx calls fn with a Tx for txn and closes the cursors of the Tx after fn
+// returns, as bbolt cursors are never closed explicitly.
+func runTx(db *DB, txn *mdbx.Txn, writable bool, fn func(*Tx) error) error {
+	txn.RawRead = true
+	tx := &Tx{db: db, txn: txn, writable: writable}
+	defer tx.close()
+	return fn(tx)
+}
+
+func (tx *Tx) close() {
+	for _, cur := range tx.cursors {
+		cur.Close()
+	}
+	tx.cursors = nil
+}
+
+// openCursor opens a cursor which is closed with the Tx.
+func (tx *Tx) openCursor(dbi mdbx.DBI) (*mdbx.Cursor, error) {
+	cur, err := tx.txn.OpenCursor(dbi)
+	if err != nil {
+		return nil, err
+	}
+	tx.cursors = append(tx.cursors, cur)
+	return cur, nil
+}
+
+// DB returns a reference to the database that created the transaction.
+func (tx *Tx) DB() *DB {
+	return tx.db
+}
+
+// Writable returns whether the transaction can perform write operations.
+func (tx *Tx) Writable() bool {
+	return tx.writable
+}
+
+// Txn returns the underlying MDBX transaction.
+func (tx *Tx) Txn() *mdbx.Txn {
+	return tx.txn
+}
+
+// Bucket retrieves a top-level bucket by name.  Returns nil if the bucket does
+// not exist.
+func (tx *Tx) Bucket(name []byte) *Bucket {
+	if len(name) == 0 || bytes.IndexByte(name, 0) >= 0 {
+		return nil
+	}
+	dbi, err := tx.txn.OpenDBI(string(name), 0)
+	if err != nil {
+		return nil
+	}
+	return &Bucket{tx: tx, dbi: dbi}
+}
+
+// CreateBucket creates a new top-level bucket.  Returns an error if the bucket
+// already exists, if the bucket name is blank, or if the bucket name is too
+// long.
+func (tx *Tx) CreateBucket(name []byte) (*Bucket, error) {
+	if err := tx.checkBucketName(name); err != nil {
+		return nil, err
+	}
+	if tx.Bucket(name) != nil {
+		return nil, ErrBucketExists
+	}
+	return tx.createBucket(name)
+}
+
+// CreateBucketIfNotExists creates a new top-level bucket if it doesn't already
+// exist and returns a reference to it.
+func (tx *Tx) CreateBucketIfNotExists(name []byte) (*Bucket, error) {
+	if err := tx.checkBucketName(name); err != nil {
+		return nil, err
+	}
+	if b := tx.Bucket(name); b != nil {
+		return b, nil
+	}
+	return tx.createBucket(name)
+}
+
+func (tx *Tx) checkBucketName(name []byte) error {
+	if !tx.writable {
+		return ErrTxNotWritable
+	}
+	if len(name) == 0 {
+		return ErrBucketNameRequired
+	}
+	if bytes.IndexByte(name, 0) >= 0 {
+		return ErrBucketNameInvalid
+	}
+	return nil
+}
+
+func (tx *Tx) createBucket(name []byte) (*Bucket, error) {
+	dbi, err := tx.txn.OpenDBI(string(name), mdbx.Create)
+	if err != nil {
+		return nil, err
+	}
+	return &Bucket{tx: tx, dbi: dbi}, nil
+}
+
+// DeleteBucket deletes a top-level bucket.  Returns an error if the bucket
+// cannot be found.
+func (tx *Tx) DeleteBucket(name []byte) error {
+	if !tx.writable {
+		return ErrTxNotWritable
+	}
+	b := tx.Bucket(name)
+	if b == nil {
+		return ErrBucketNotFound
+	}
+	return tx.txn.Drop(b.dbi, true)
+}
+
+// ForEach executes a function for each top-level bucket, in order.  If the
+// provided function returns an error then the iteration is stopped and the
+// error is returned to the caller.
+func (tx *Tx) ForEach(fn func(name []byte, b *Bucket) error) error {
+	names, err := tx.txn.ListDBIs()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		b := tx.Bucket([]byte(name))
+		if b == nil {
+			continue
+		}
+		if err := fn([]byte(name), b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
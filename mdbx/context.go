@@ -0,0 +1,94 @@
+package mdbx
+
+import (
+	"context"
+	"fmt"
+)
+
+// ViewContext behaves like View but stops the transaction when ctx is done.
+// If ctx is done while fn is running the transaction is broken (see
+// Txn.Break), so that operations on it fail fast, and it is aborted once fn
+// returns.  The error returned when ctx is done wraps ctx.Err(), so it may be
+// tested with errors.Is(err, context.Canceled) and the like.
+//
+// Iteration helpers such as Txn.Scan and Iterator check ctx between items.
+func (env *Env) ViewContext(ctx context.Context, fn TxnOp) error {
+	return env.runContext(ctx, false, Readonly, fn)
+}
+
+// UpdateContext behaves like Update but aborts the transaction instead of
+// committing it when ctx is done.  A write transaction cannot be interrupted
+// from another goroutine, so ctx is checked before fn is called, by the
+// iteration helpers such as Txn.Scan, and after fn returns.  Long running
+// operations should check Txn.Context themselves.  The error returned when
+// ctx is done wraps ctx.Err().
+func (env *Env) UpdateContext(ctx context.Context, fn TxnOp) error {
+	return env.runContext(ctx, true, 0, fn)
+}
+
+func (env *Env) runContext(ctx context.Context, lock bool, flags uint, fn TxnOp) error {
+	if err := ctx.Err(); err != nil {
+		return contextError(err)
+	}
+	return env.run(lock, flags, func(txn *Txn) error {
+		txn.ctx = ctx
+		if txn.readonly {
+			stop := txn.breakOnDone()
+			defer stop()
+		}
+		err := fn(txn)
+		if cerr := txn.ctxErr(); cerr != nil {
+			return cerr
+		}
+		return err
+	})
+}
+
+// breakOnDone breaks txn when its context is done.  The returned function
+// must be called before txn is terminated.
+func (txn *Txn) breakOnDone() (stop func()) {
+	done := txn.ctx.Done()
+	if done == nil {
+		return func() {}
+	}
+	stopc := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		select {
+		case <-done:
+			txn.Break()
+		case <-stopc:
+		}
+	}()
+	return func() {
+		close(stopc)
+		<-exited
+	}
+}
+
+// Context returns the context of txn, which is context.Background() unless
+// txn was created by ViewContext or UpdateContext or is a subtransaction of
+// one that was.
+func (txn *Txn) Context() context.Context {
+	if txn.ctx == nil {
+		return context.Background()
+	}
+	return txn.ctx
+}
+
+// ctxErr returns an error wrapping the error of the context of txn if it is
+// done, and nil otherwise.
+func (txn *Txn) ctxErr() error {
+	if txn.ctx == nil {
+		return nil
+	}
+	if err := txn.ctx.Err(); err != nil {
+		return contextError(err)
+	}
+	return nil
+}
+
+func contextError(err error) error {
+	return fmt.Errorf("mdbx: transaction cancelled: %w", err)
+}
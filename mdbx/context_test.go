@@ -0,0 +1,125 @@
+package mdbx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestEnv_ViewContext(t *testing.T) {
+	env, _, teardown := setup(t)
+	defer teardown()
+
+	var dbi DBI
+	err := env.Update(func(txn *Txn) (err error) {
+		dbi, err = txn.OpenRoot(0)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < 100; i++ {
+			if err = txn.Put(dbi, []byte{byte(i)}, []byte{byte(i)}, 0); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	called := false
+	err = env.ViewContext(ctx, func(txn *Txn) error {
+		called = true
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) || called {
+		t.Errorf("cancelled view: %v (called %v)", err, called)
+	}
+
+	// a scan stops once the context is cancelled
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	n := 0
+	err = env.ViewContext(ctx, func(txn *Txn) error {
+		if txn.Context() != ctx {
+			t.Errorf("unexpected context")
+		}
+		return txn.Scan(dbi, ScanOptions{}, func(k, v []byte) (bool, error) {
+			n++
+			if n == 10 {
+				cancel()
+			}
+			return true, nil
+		})
+	})
+	if !errors.Is(err, context.Canceled) || n != 10 {
+		t.Errorf("scan: %v after %d items", err, n)
+	}
+
+	// a reader is broken when the deadline expires
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = env.ViewContext(ctx, func(txn *Txn) error {
+		<-ctx.Done()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			_, err := txn.Get(dbi, []byte{0})
+			if IsErrno(err, BadTxn) {
+				return err
+			}
+			if time.Now().After(deadline) {
+				t.Errorf("transaction was not broken: %v", err)
+				return nil
+			}
+			time.Sleep(time.Millisecond)
+		}
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestEnv_UpdateContext(t *testing.T) {
+	env, _, teardown := setup(t)
+	defer teardown()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err := env.UpdateContext(ctx, func(txn *Txn) error {
+		dbi, err := txn.OpenRoot(0)
+		if err != nil {
+			return err
+		}
+		err = txn.Sub(func(sub *Txn) error {
+			if sub.Context() != ctx {
+				t.Errorf("subtransaction does not inherit the context")
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		cancel()
+		return txn.Put(dbi, []byte("k"), []byte("v"), 0)
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	err = env.View(func(txn *Txn) error {
+		dbi, err := txn.OpenRoot(0)
+		if err != nil {
+			return err
+		}
+		_, err = txn.Get(dbi, []byte("k"))
+		if !IsNotFound(err) {
+			t.Errorf("cancelled update was committed: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,257 @@
+package mdbx
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCursor_PutReserve(t *testing.T) {
+	env, _, teardown := setup(t)
+	defer teardown()
+
+	err := env.Update(func(txn *Txn) error {
+		dbi, err := txn.OpenRoot(0)
+		if err != nil {
+			return err
+		}
+		cur, err := txn.OpenCursor(dbi)
+		if err != nil {
+			return err
+		}
+		defer cur.Close()
+
+		p, err := cur.PutReserve([]byte("k"), 3, 0)
+		if err != nil {
+			return err
+		}
+		copy(p, "abc")
+		k, v, err := cur.Get(nil, nil, GetCurrent)
+		if err != nil {
+			return err
+		}
+		if string(k) != "k" || string(v) != "abc" {
+			t.Errorf("current item %q=%q", k, v)
+		}
+		p, err = cur.PutReserve([]byte("k"), 2, Current)
+		if err != nil {
+			return err
+		}
+		copy(p, "xy")
+		v, err = txn.Get(dbi, []byte("k"))
+		if err == nil && string(v) != "xy" {
+			t.Errorf("value %q", v)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCursor_PutMulti(t *testing.T) {
+	env, _, teardown := setup(t)
+	defer teardown()
+
+	const stride = 4
+	var page []byte
+	for i := 0; i < 100; i++ {
+		page = append(page, byte(i>>24), byte(i>>16), byte(i>>8), byte(i))
+	}
+
+	err := env.Update(func(txn *Txn) error {
+		dbi, err := txn.OpenDBI("multi", Create|DupSort|DupFixed)
+		if err != nil {
+			return err
+		}
+		cur, err := txn.OpenCursor(dbi)
+		if err != nil {
+			return err
+		}
+		defer cur.Close()
+
+		if err = cur.PutMulti([]byte("k"), page, stride, 0); err != nil {
+			return err
+		}
+		if n, err := cur.Count(); err != nil || n != 100 {
+			t.Errorf("count %d, %v", n, err)
+		}
+
+		var got []byte
+		_, first, err := cur.Get([]byte("k"), nil, Set)
+		if err != nil {
+			return err
+		}
+		for op := uint(GetMultiple); ; op = NextMultiple {
+			_, p, err := cur.Get(nil, nil, op)
+			if IsNotFound(err) {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			m := WrapMulti(p, len(first))
+			for _, v := range m.Vals() {
+				got = append(got, v...)
+			}
+		}
+		if !bytes.Equal(got, page) {
+			t.Errorf("values %x, want %x", got, page)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestCursor_PutMulti_Existing checks PutMulti on keys which already hold
+// zero, one or more of the values, as MDBX_MULTIPLE fails in the bundled
+// libmdbx when it converts a single value into a sub-page.
+func TestCursor_PutMulti_Existing(t *testing.T) {
+	env, _, teardown := setup(t)
+	defer teardown()
+
+	const stride = 4
+	page := func(from, to int) []byte {
+		var p []byte
+		for i := from; i < to; i++ {
+			p = append(p, byte(i>>24), byte(i>>16), byte(i>>8), byte(i))
+		}
+		return p
+	}
+
+	for _, test := range []struct {
+		before   []byte
+		put      []byte
+		expected []byte
+	}{
+		{nil, page(0, 3), page(0, 3)},
+		{nil, page(0, 5000), page(0, 5000)},
+		{page(0, 1), page(1, 4), page(0, 4)},
+		{page(5, 6), page(0, 10), page(0, 10)},
+		{page(0, 2), page(2, 100), page(0, 100)},
+		{page(0, 3), page(1, 11), page(0, 11)},
+		{page(0, 1000), page(500, 1500), page(0, 1500)},
+	} {
+		err := env.Update(func(txn *Txn) error {
+			dbi, err := txn.OpenDBI("multi", Create|DupSort|DupFixed)
+			if err != nil {
+				return err
+			}
+			defer txn.Drop(dbi, false)
+			cur, err := txn.OpenCursor(dbi)
+			if err != nil {
+				return err
+			}
+			defer cur.Close()
+
+			if len(test.before) > 0 {
+				if err = cur.PutMulti([]byte("k"), test.before, stride, 0); err != nil {
+					return err
+				}
+			}
+			if err = cur.PutMulti([]byte("k"), test.put, stride, 0); err != nil {
+				return err
+			}
+			var got []byte
+			for op := uint(First); ; op = Next {
+				_, v, err := cur.Get(nil, nil, op)
+				if IsNotFound(err) {
+					break
+				}
+				if err != nil {
+					return err
+				}
+				got = append(got, v...)
+			}
+			if !bytes.Equal(got, test.expected) {
+				t.Errorf("%d before %d: got %d values", len(test.before)/stride, len(test.put)/stride, len(got)/stride)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%d before %d: %v", len(test.before)/stride, len(test.put)/stride, err)
+		}
+	}
+
+	err := env.Update(func(txn *Txn) error {
+		dbi, err := txn.OpenDBI("multi", Create|DupSort|DupFixed)
+		if err != nil {
+			return err
+		}
+		cur, err := txn.OpenCursor(dbi)
+		if err != nil {
+			return err
+		}
+		defer cur.Close()
+
+		if err = cur.PutMulti([]byte("k"), page(0, 5), stride, 0); err != nil {
+			return err
+		}
+		err = cur.PutMulti([]byte("k"), page(3, 10), stride, NoDupData)
+		if !IsErrno(err, KeyExist) {
+			t.Errorf("NoDupData: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCursor_Renew(t *testing.T) {
+	env, _, teardown := setup(t)
+	defer teardown()
+
+	var dbi DBI
+	put := func(v string) {
+		err := env.Update(func(txn *Txn) (err error) {
+			dbi, err = txn.OpenRoot(0)
+			if err != nil {
+				return err
+			}
+			return txn.Put(dbi, []byte("k"), []byte(v), 0)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	get := func(cur *Cursor) string {
+		_, v, err := cur.Get([]byte("k"), nil, SetKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(v)
+	}
+
+	put("1")
+	txn, err := env.BeginTxn(nil, Readonly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer txn.Abort()
+	cur, err := txn.OpenCursor(dbi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cur.Close()
+	if v := get(cur); v != "1" {
+		t.Errorf("value %q, want 1", v)
+	}
+
+	put("2")
+	txn2, err := env.BeginTxn(nil, Readonly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer txn2.Abort()
+	if err = cur.Renew(txn2); err != nil {
+		t.Fatal(err)
+	}
+	if cur.Txn() != txn2 {
+		t.Error("cursor not bound to the new transaction")
+	}
+	if v := get(cur); v != "2" {
+		t.Errorf("value %q, want 2", v)
+	}
+}
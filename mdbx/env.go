@@ -4,12 +4,17 @@ package mdbx
 #include <stdlib.h>
 #include <stdio.h>
 #include "mdbx.h"
+#include "mdbxgo.h"
 */
 import "C"
 
 import (
 	"errors"
+	"fmt"
+	"os"
 	"runtime"
+	"sort"
+	"strconv"
 	"sync"
 	"unsafe"
 )
@@ -21,14 +26,15 @@ const (
 	//
 	// See mdbx_env_open
 
-	NoSubdir    = C.MDBX_NOSUBDIR   // Argument to Open is a file, not a directory.
-	Readonly    = C.MDBX_RDONLY     // Used in several functions to denote an object as readonly.
-	WriteMap    = C.MDBX_WRITEMAP   // Use a writable memory map.
-	NoMetaSync  = C.MDBX_NOMETASYNC // Don't fsync metapage after commit.
-	MapAsync    = C.MDBX_MAPASYNC   // Flush asynchronously when using the WriteMap flag.
-	NoTLS       = C.MDBX_NOTLS      // Danger zone. When unset reader locktable slots are tied to their thread.
-	NoReadahead = C.MDBX_NORDAHEAD  // Disable readahead. Requires OS support.
-	NoMemInit   = C.MDBX_NOMEMINIT  // Disable MDBX memory initialization.
+	NoSubdir    = C.MDBX_NOSUBDIR    // Argument to Open is a file, not a directory.
+	Readonly    = C.MDBX_RDONLY      // Used in several functions to denote an object as readonly.
+	WriteMap    = C.MDBX_WRITEMAP    // Use a writable memory map.
+	NoMetaSync  = C.MDBX_NOMETASYNC  // Don't fsync metapage after commit.
+	NoSync      = C.MDBX_SAFE_NOSYNC // Don't fsync after commit.
+	MapAsync    = C.MDBX_MAPASYNC    // Flush asynchronously when using the WriteMap flag.
+	NoTLS       = C.MDBX_NOTLS       // Danger zone. When unset reader locktable slots are tied to their thread.
+	NoReadahead = C.MDBX_NORDAHEAD   // Disable readahead. Requires OS support.
+	NoMemInit   = C.MDBX_NOMEMINIT   // Disable MDBX memory initialization.
 )
 
 // DBI is a handle for a database in an Env.
@@ -43,6 +49,22 @@ type DBI C.MDBX_dbi
 type Env struct {
 	_env *C.MDBX_env
 
+	// geo holds the arguments of the last successful SetGeometry call.
+	geo geometry
+
+	// tls is true if the environment was opened without NoTLS, so that its
+	// reader slots are tied to OS threads.
+	tls bool
+
+	// batches collects the calls of Env.Batch.
+	batches batcher
+
+	// grow is the AutoGrow policy applied to write transactions.
+	grow autoGrower
+
+	// dbis caches the handles opened by Env.DBI.
+	dbis dbiRegistry
+
 	// closeLock is used to allow the Txn finalizer to check if the Env has
 	// been closed, so that it may know if it must abort.
 	closeLock sync.RWMutex
@@ -51,10 +73,14 @@ type Env struct {
 	cval *C.MDBX_val
 }
 
-// NewEnv allocates and initializes a new Env.
+// NewEnv allocates and initializes a new Env.  NewEnv fails if the linked
+// libmdbx does not match the headers, see CheckVersion.
 //
 // See mdbx_env_create.
 func NewEnv() (*Env, error) {
+	if err := CheckVersion(); err != nil {
+		return nil, err
+	}
 	env := new(Env)
 	ret := C.mdbx_env_create(&env._env)
 	if ret != success {
@@ -70,16 +96,235 @@ func NewEnv() (*Env, error) {
 // Open an environment handle. If this function fails Close() must be called to
 // discard the Env handle.
 //
+// Open is equivalent to calling OpenWithOptions with zero OpenOptions.
+//
 // See mdbx_env_open.
 func (env *Env) Open(path string) error {
+	return env.OpenWithOptions(path, OpenOptions{})
+}
+
+// ReadaheadMode selects whether an environment is opened with the
+// NoReadahead flag.
+type ReadaheadMode int
+
+// Readahead modes for OpenOptions.
+const (
+	// ReadaheadOff always passes NoReadahead, as Open does.
+	ReadaheadOff ReadaheadMode = iota
+	// ReadaheadOn leaves readahead to the operating system.
+	ReadaheadOn
+	// ReadaheadAuto turns readahead on only if the expected size of the
+	// database fits into available RAM, see Env.ReadaheadReasonable.
+	ReadaheadAuto
+)
+
+// OpenOptions configure how OpenWithOptions opens an environment.  The zero
+// value opens it the same way Open does.
+type OpenOptions struct {
+	// Flags are added to the flags always used by Open, e.g. WriteMap.
+	Flags uint
+
+	// Mode is the file mode for newly created database files, 0664 if zero.
+	Mode os.FileMode
+
+	// Readahead is the readahead mode.  Passing NoReadahead in Flags turns
+	// readahead off regardless.
+	Readahead ReadaheadMode
+
+	// ExpectedSize is the expected size of the database in bytes used by
+	// ReadaheadAuto.  If zero the upper size passed to SetGeometry is used,
+	// and readahead stays off if neither is known.
+	ExpectedSize int64
+
+	// TLS opens the environment without NoTLS, tying reader slots to OS
+	// threads.  Goroutines may then migrate between threads in the middle of
+	// a readonly transaction, unless they use Env.ViewThread or lock their
+	// thread otherwise.  Doing so results in ThreadMismatch errors or
+	// corrupted reader slot ownership.
+	TLS bool
+}
+
+// openFlags are the flags Open always passes to mdbx_env_open.
+const openFlags = C.MDBX_NOSUBDIR | C.MDBX_COALESCE | C.MDBX_LIFORECLAIM
+
+// OpenWithOptions opens an environment handle as configured by opts. If this
+// function fails Close() must be called to discard the Env handle.
+//
+// See mdbx_env_open.
+func (env *Env) OpenWithOptions(path string, opts OpenOptions) error {
+	flags := uint(openFlags) | opts.Flags
+	if !opts.TLS {
+		flags |= NoTLS
+	}
+	readahead, err := env.readahead(opts)
+	if err != nil {
+		return err
+	}
+	if !readahead {
+		flags |= NoReadahead
+	}
+	mode := opts.Mode
+	if mode == 0 {
+		mode = 0664
+	}
+
 	cpath := C.CString(path)
 	defer C.free(unsafe.Pointer(cpath))
-	ret := C.mdbx_env_open(env._env, cpath, C.MDBX_NOSUBDIR|C.MDBX_COALESCE|C.MDBX_LIFORECLAIM|C.MDBX_NORDAHEAD|C.MDBX_NOTLS, 0664)
-	return operrno("mdbx_env_open", ret)
+	ret := C.mdbx_env_open(env._env, cpath, C.MDBX_env_flags_t(flags), C.mdbx_mode_t(mode))
+	if ret != success {
+		return operrno("mdbx_env_open", ret)
+	}
+	env.tls = flags&NoTLS == 0
+	return nil
+}
+
+// RegisterThread assigns a reader slot of env to the calling OS thread in
+// advance, which otherwise happens when the thread starts its first readonly
+// transaction.  RegisterThread returns true if the thread was not registered
+// before.  It fails unless env was opened with OpenOptions.TLS.
+//
+// The calling goroutine must be locked to its thread, see
+// runtime.LockOSThread.
+//
+// See mdbx_thread_register.
+func (env *Env) RegisterThread() (bool, error) {
+	ret := C.mdbx_thread_register(env._env)
+	if ret == C.MDBX_RESULT_TRUE {
+		return false, nil
+	}
+	return ret == success, operrno("mdbx_thread_register", ret)
+}
+
+// UnregisterThread releases the reader slot of env assigned to the calling OS
+// thread.  It does nothing if the thread is not registered or env was opened
+// without OpenOptions.TLS.
+//
+// See mdbx_thread_unregister.
+func (env *Env) UnregisterThread() error {
+	ret := C.mdbx_thread_unregister(env._env)
+	if ret == C.MDBX_RESULT_TRUE {
+		return nil
+	}
+	return operrno("mdbx_thread_unregister", ret)
+}
+
+func (env *Env) readahead(opts OpenOptions) (bool, error) {
+	switch opts.Readahead {
+	case ReadaheadOn:
+		return true, nil
+	case ReadaheadAuto:
+		volume := opts.ExpectedSize
+		if volume <= 0 {
+			volume = int64(env.geo.sizeUpper)
+		}
+		if volume <= 0 {
+			return false, nil
+		}
+		return readaheadReasonable(volume)
+	default:
+		return false, nil
+	}
+}
+
+// ReadaheadReasonable returns true if readahead is likely to help an
+// environment of volume bytes, given the amount of RAM currently available.
+// If volume is not positive the current size of the datafile of env is used,
+// which requires env to be open.
+//
+// See mdbx_is_readahead_reasonable.
+func (env *Env) ReadaheadReasonable(volume int64) (bool, error) {
+	if volume <= 0 {
+		info, err := env.Info()
+		if err != nil {
+			return false, err
+		}
+		volume = int64(info.Geo.Current)
+	}
+	return readaheadReasonable(volume)
+}
+
+func readaheadReasonable(volume int64) (bool, error) {
+	ret := C.mdbx_is_readahead_reasonable(C.size_t(volume), 0)
+	switch ret {
+	case C.MDBX_RESULT_TRUE:
+		return true, nil
+	case C.MDBX_RESULT_FALSE:
+		return false, nil
+	default:
+		return false, operrno("mdbx_is_readahead_reasonable", ret)
+	}
+}
+
+// SysRAMInfo describes the RAM of the system.
+type SysRAMInfo struct {
+	PageSize   int64 // System page size in bytes
+	TotalPages int64 // Number of RAM pages
+	AvailPages int64 // Number of available/free RAM pages
+}
+
+// Total returns the amount of RAM in bytes.
+func (info *SysRAMInfo) Total() int64 {
+	return info.PageSize * info.TotalPages
+}
+
+// Avail returns the amount of available RAM in bytes.
+func (info *SysRAMInfo) Avail() int64 {
+	return info.PageSize * info.AvailPages
+}
+
+// SysRAM returns information about the RAM of the system, as used by MDBX to
+// control readahead.
+//
+// See mdbx_get_sysraminfo.
+func SysRAM() (*SysRAMInfo, error) {
+	var pageSize, totalPages, availPages C.intptr_t
+	ret := C.mdbx_get_sysraminfo(&pageSize, &totalPages, &availPages)
+	if ret != success {
+		return nil, operrno("mdbx_get_sysraminfo", ret)
+	}
+	return &SysRAMInfo{
+		PageSize:   int64(pageSize),
+		TotalPages: int64(totalPages),
+		AvailPages: int64(availPages),
+	}, nil
+}
+
+// OpenForRecovery opens the environment at path using the meta page target
+// instead of the most recent one, so that a database with a damaged latest
+// commit can still be inspected.  The environment is always opened in
+// exclusive mode, and readonly unless writable is true.  A writable
+// environment may then be rolled back to target by calling TurnForRecovery.
+//
+// OpenForRecovery must be called instead of Open on a new Env.  If this
+// function fails Close() must be called to discard the Env handle.
+//
+// See mdbx_env_open_for_recovery.
+func (env *Env) OpenForRecovery(path string, target int, writable bool) error {
+	if target < 0 {
+		return errNegTarget
+	}
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+	ret := C.mdbx_env_open_for_recovery(env._env, cpath, C.uint(target), cbool(writable))
+	return operrno("mdbx_env_open_for_recovery", ret)
+}
+
+// TurnForRecovery makes the meta page target the most recent one, discarding
+// any later commits.  The environment must have been opened by
+// OpenForRecovery with writable set to true.
+//
+// See mdbx_env_turn_for_recovery.
+func (env *Env) TurnForRecovery(target int) error {
+	if target < 0 {
+		return errNegTarget
+	}
+	ret := C.mdbx_env_turn_for_recovery(env._env, C.uint(target))
+	return operrno("mdbx_env_turn_for_recovery", ret)
 }
 
 var errNotOpen = errors.New("enivornment is not open")
 var errNegSize = errors.New("negative size")
+var errNegTarget = errors.New("negative meta page target")
 
 func (env *Env) close() bool {
 	if env._env == nil {
@@ -114,10 +359,54 @@ func (env *Env) SetGeometry(size_lower, size_now, size_upper, growth_step, shrin
 	if size_upper < 0 || pagesize < 0 {
 		return errNegSize
 	}
+	return env.setGeometry(geometry{size_lower, size_now, size_upper, growth_step, shrink_threshold, pagesize})
+}
+
+func (env *Env) setGeometry(g geometry) error {
 	ret := C.mdbx_env_set_geometry(env._env,
-		C.long(size_lower), C.long(size_now), C.long(size_upper),
-		C.long(growth_step), C.long(shrink_threshold), C.long(pagesize))
-	return operrno("mdbx_env_set_geometry", ret)
+		C.long(g.sizeLower), C.long(g.sizeNow), C.long(g.sizeUpper),
+		C.long(g.growthStep), C.long(g.shrinkThreshold), C.long(g.pagesize))
+	if ret != success {
+		return operrno("mdbx_env_set_geometry", ret)
+	}
+	env.geo.update(g)
+	return nil
+}
+
+// SetMapSize sets the size of the memory map, which is both the current and
+// the maximum size of the database.  Unlike LMDB the database file is resized
+// at once.  SetGeometry gives finer control over the way the database grows.
+//
+// SetMapSize may be called on an open environment when no transactions are
+// active in the process.  Without OpenOptions.TLS the map can only be resized
+// in place, which may fail with UnableExtendMapsize.
+//
+// See mdbx_env_set_mapsize.
+func (env *Env) SetMapSize(size int64) error {
+	if size < 0 {
+		return errNegSize
+	}
+	return env.setGeometry(geometry{-1, int(size), int(size), -1, -1, -1})
+}
+
+// geometry holds the arguments of mdbx_env_set_geometry.  Negative values
+// leave the corresponding setting unchanged.
+type geometry struct {
+	sizeLower, sizeNow, sizeUpper, growthStep, shrinkThreshold, pagesize int
+}
+
+func (geo *geometry) update(g geometry) {
+	set := func(dst *int, v int) {
+		if v >= 0 {
+			*dst = v
+		}
+	}
+	set(&geo.sizeLower, g.sizeLower)
+	set(&geo.sizeNow, g.sizeNow)
+	set(&geo.sizeUpper, g.sizeUpper)
+	set(&geo.growthStep, g.growthStep)
+	set(&geo.shrinkThreshold, g.shrinkThreshold)
+	set(&geo.pagesize, g.pagesize)
 }
 
 // Path returns the path argument passed to Open.  Path returns a non-nil error
@@ -136,6 +425,341 @@ func (env *Env) Path() (string, error) {
 	return C.GoString(cpath), nil
 }
 
+// MetaPage describes one of the three meta pages of an environment.  Each
+// commit is recorded in one of them, so together they hold the three most
+// recent states the database may be recovered to.
+type MetaPage struct {
+	Target int    // Index of the page, as accepted by OpenForRecovery and TurnForRecovery
+	Txnid  uint64 // ID of the transaction recorded in the page
+	Sign   uint64 // Data signature of the page
+}
+
+// Steady returns true if the page was synced to disk together with the data
+// it refers to.  Only steady pages are safe recovery targets after a system
+// crash.
+func (m MetaPage) Steady() bool {
+	return m.Sign > 1
+}
+
+// EnvInfo contains information about an environment.
+//
+// See MDBX_envinfo.
+type EnvInfo struct {
+	Geo struct {
+		Lower   uint64 // Lower limit for datafile size
+		Upper   uint64 // Upper limit for datafile size
+		Current uint64 // Current datafile size
+		Shrink  uint64 // Shrink threshold for datafile
+		Grow    uint64 // Growth step for datafile
+	}
+	MapSize               int64 // Size of the data memory map
+	LastPNO               int64 // ID of the last used page
+	RecentTxnID           int64 // ID of the last committed transaction
+	LastTxnID             int64 // Same as RecentTxnID, under its LMDB name
+	LatterReaderTxnID     int64 // ID of the last reader transaction
+	SelfLatterReaderTxnID int64 // ID of the last reader transaction of caller process
+	Meta                  [3]MetaPage
+	MaxReaders            uint   // Total reader slots in the environment
+	NumReaders            uint   // Max reader slots used in the environment
+	PageSize              uint   // Database pagesize
+	SystemPageSize        uint   // System pagesize
+	UnsyncVolume          uint64 // Bytes not explicitly synchronized to disk
+	Mode                  uint   // Flags the environment was opened with
+}
+
+// MetaPages returns the meta pages of the environment in order of their
+// transaction ids, most recent first.  It is meant to help an operator
+// choose a target for OpenForRecovery and TurnForRecovery.
+func (info *EnvInfo) MetaPages() []MetaPage {
+	metas := append([]MetaPage(nil), info.Meta[:]...)
+	sort.Slice(metas, func(i, j int) bool { return metas[i].Txnid > metas[j].Txnid })
+	return metas
+}
+
+// Info returns information about the environment.
+//
+// See mdbx_env_info_ex.
+func (env *Env) Info() (*EnvInfo, error) {
+	var _info C.MDBX_envinfo
+	ret := C.mdbx_env_info_ex(env._env, nil, &_info, C.size_t(unsafe.Sizeof(_info)))
+	if ret != success {
+		return nil, operrno("mdbx_env_info_ex", ret)
+	}
+	info := &EnvInfo{
+		MapSize:               int64(_info.mi_mapsize),
+		LastPNO:               int64(_info.mi_last_pgno),
+		RecentTxnID:           int64(_info.mi_recent_txnid),
+		LastTxnID:             int64(_info.mi_recent_txnid),
+		LatterReaderTxnID:     int64(_info.mi_latter_reader_txnid),
+		SelfLatterReaderTxnID: int64(_info.mi_self_latter_reader_txnid),
+		Meta: [3]MetaPage{
+			{Target: 0, Txnid: uint64(_info.mi_meta0_txnid), Sign: uint64(_info.mi_meta0_sign)},
+			{Target: 1, Txnid: uint64(_info.mi_meta1_txnid), Sign: uint64(_info.mi_meta1_sign)},
+			{Target: 2, Txnid: uint64(_info.mi_meta2_txnid), Sign: uint64(_info.mi_meta2_sign)},
+		},
+		MaxReaders:     uint(_info.mi_maxreaders),
+		NumReaders:     uint(_info.mi_numreaders),
+		PageSize:       uint(_info.mi_dxb_pagesize),
+		SystemPageSize: uint(_info.mi_sys_pagesize),
+		UnsyncVolume:   uint64(_info.mi_unsync_volume),
+		Mode:           uint(_info.mi_mode),
+	}
+	info.Geo.Lower = uint64(_info.mi_geo.lower)
+	info.Geo.Upper = uint64(_info.mi_geo.upper)
+	info.Geo.Current = uint64(_info.mi_geo.current)
+	info.Geo.Shrink = uint64(_info.mi_geo.shrink)
+	info.Geo.Grow = uint64(_info.mi_geo.grow)
+	return info, nil
+}
+
+// Stat contains database status information.
+//
+// See MDBX_stat.
+type Stat struct {
+	PSize         uint   // Size of a database page. This is currently the same for all databases.
+	Depth         uint   // Depth (height) of the B-tree
+	BranchPages   uint64 // Number of internal (non-leaf) pages
+	LeafPages     uint64 // Number of leaf pages
+	OverflowPages uint64 // Number of overflow pages
+	Entries       uint64 // Number of data items
+	ModTxnID      uint64 // ID of the transaction which last modified the database
+}
+
+func newStat(_stat *C.MDBX_stat) *Stat {
+	return &Stat{
+		PSize:         uint(_stat.ms_psize),
+		Depth:         uint(_stat.ms_depth),
+		BranchPages:   uint64(_stat.ms_branch_pages),
+		LeafPages:     uint64(_stat.ms_leaf_pages),
+		OverflowPages: uint64(_stat.ms_overflow_pages),
+		Entries:       uint64(_stat.ms_entries),
+		ModTxnID:      uint64(_stat.ms_mod_txnid),
+	}
+}
+
+// Stat returns statistics about the root database of the environment.  Use
+// Txn.Stat for a named database.
+//
+// See mdbx_env_stat_ex.
+func (env *Env) Stat() (*Stat, error) {
+	var _stat C.MDBX_stat
+	ret := C.mdbx_env_stat_ex(env._env, nil, &_stat, C.size_t(unsafe.Sizeof(_stat)))
+	if ret != success {
+		return nil, operrno("mdbx_env_stat_ex", ret)
+	}
+	return newStat(&_stat), nil
+}
+
+const (
+	// Flags for Env.CopyFlag.
+	//
+	// See mdbx_env_copy.
+
+	CopyCompact          = C.MDBX_CP_COMPACT            // Omit free pages and renumber all pages.
+	CopyForceDynamicSize = C.MDBX_CP_FORCE_DYNAMIC_SIZE // Make the copy resizable even if env has a fixed size.
+)
+
+// Copy copies the data in env to the file at path, which must not exist.
+// Unlike LMDB path names a file, as environments are opened with NoSubdir.
+//
+// See mdbx_env_copy.
+func (env *Env) Copy(path string) error {
+	return env.CopyFlag(path, 0)
+}
+
+// CopyFlag copies the data in env to the file at path, which must not exist,
+// passing flags such as CopyCompact.
+//
+// See mdbx_env_copy.
+func (env *Env) CopyFlag(path string, flags uint) error {
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+	ret := C.mdbx_env_copy(env._env, cpath, C.MDBX_copy_flags_t(flags))
+	return operrno("mdbx_env_copy", ret)
+}
+
+// CopyFD copies env to the file descriptor fd.
+//
+// See mdbx_env_copy2fd.
+func (env *Env) CopyFD(fd uintptr) error {
+	return env.CopyFDFlag(fd, 0)
+}
+
+// CopyFDFlag copies env to the file descriptor fd, passing flags such as
+// CopyCompact.
+//
+// See mdbx_env_copy2fd.
+func (env *Env) CopyFDFlag(fd uintptr, flags uint) error {
+	ret := C.mdbx_env_copy2fd(env._env, C.mdbx_filehandle_t(fd), C.MDBX_copy_flags_t(flags))
+	return operrno("mdbx_env_copy2fd", ret)
+}
+
+// Sync flushes buffers to disk.  If force is true a synchronous flush occurs
+// even if the environment was opened with NoSync or NoMetaSync.
+//
+// See mdbx_env_sync_ex.
+func (env *Env) Sync(force bool) error {
+	ret := C.mdbx_env_sync_ex(env._env, C.bool(force), C.bool(false))
+	if ret == C.MDBX_RESULT_TRUE {
+		// there was nothing to flush
+		return nil
+	}
+	return operrno("mdbx_env_sync_ex", ret)
+}
+
+// SetFlags sets flags in the environment, such as NoSync.
+//
+// See mdbx_env_set_flags.
+func (env *Env) SetFlags(flags uint) error {
+	ret := C.mdbx_env_set_flags(env._env, C.MDBX_env_flags_t(flags), C.bool(true))
+	return operrno("mdbx_env_set_flags", ret)
+}
+
+// UnsetFlags clears flags in the environment.
+//
+// See mdbx_env_set_flags.
+func (env *Env) UnsetFlags(flags uint) error {
+	ret := C.mdbx_env_set_flags(env._env, C.MDBX_env_flags_t(flags), C.bool(false))
+	return operrno("mdbx_env_set_flags", ret)
+}
+
+// Flags returns the flags set in the environment.
+//
+// See mdbx_env_get_flags.
+func (env *Env) Flags() (uint, error) {
+	var _flags C.uint
+	ret := C.mdbx_env_get_flags(env._env, &_flags)
+	if ret != success {
+		return 0, operrno("mdbx_env_get_flags", ret)
+	}
+	return uint(_flags), nil
+}
+
+// FD returns the open file descriptor (or Windows file handle) of the
+// environment's data file.  An error is returned if the environment has not
+// been successfully opened.
+//
+// See mdbx_env_get_fd.
+func (env *Env) FD() (uintptr, error) {
+	var mf C.mdbx_filehandle_t
+	ret := C.mdbx_env_get_fd(env._env, &mf)
+	if ret == C.MDBX_EPERM {
+		// MDBX reports an environment which is not open as EPERM
+		return 0, errNotOpen
+	}
+	if ret != success {
+		return 0, operrno("mdbx_env_get_fd", ret)
+	}
+	return uintptr(mf), nil
+}
+
+// MaxKeySize returns the maximum allowed length for a key in a database
+// without the DupSort flag.  If env is nil the limit for the default page
+// size is returned.
+//
+// See mdbx_env_get_maxkeysize_ex and mdbx_limits_keysize_max.
+func (env *Env) MaxKeySize() int {
+	if env == nil {
+		return int(C.mdbx_limits_keysize_max(-1, 0))
+	}
+	return int(C.mdbx_env_get_maxkeysize_ex(env._env, 0))
+}
+
+// ReaderList dumps the contents of the reader lock table as text, in the
+// format of LMDB's mdb_reader_list.  Readers start on the second line as
+// space-delimited fields described by the first line.  If fn returns an error
+// the dump stops and the error is returned.
+//
+// See mdbx_reader_list.
+func (env *Env) ReaderList(fn func(string) error) error {
+	if fn == nil {
+		return operrno("mdbx_reader_list", C.MDBX_EINVAL)
+	}
+	r := &readerList{fn: fn}
+	ctx := registerReaderList(r)
+	defer deregisterReaderList(ctx)
+	ret := C.mdbxgo_reader_list(env._env, ctx)
+	if r.err != nil {
+		return r.err
+	}
+	if ret == C.MDBX_RESULT_TRUE {
+		// the reader lock table is empty
+		return fn("(no active readers)\n")
+	}
+	return operrno("mdbx_reader_list", ret)
+}
+
+// readerList holds the state of a single Env.ReaderList call, referenced
+// from C by an integer key.
+type readerList struct {
+	fn     func(string) error
+	err    error
+	header bool
+}
+
+var readerLists = struct {
+	sync.Mutex
+	m    map[C.size_t]*readerList
+	next C.size_t
+}{m: map[C.size_t]*readerList{}}
+
+func registerReaderList(r *readerList) C.size_t {
+	readerLists.Lock()
+	defer readerLists.Unlock()
+	readerLists.next++
+	readerLists.m[readerLists.next] = r
+	return readerLists.next
+}
+
+func deregisterReaderList(ctx C.size_t) {
+	readerLists.Lock()
+	delete(readerLists.m, ctx)
+	readerLists.Unlock()
+}
+
+func lookupReaderList(ctx C.size_t) *readerList {
+	readerLists.Lock()
+	defer readerLists.Unlock()
+	return readerLists.m[ctx]
+}
+
+//export mdbxgoReaderListBridge
+func mdbxgoReaderListBridge(ctx C.size_t, pid C.int, thread C.uint64_t, txnid C.uint64_t) C.int {
+	r := lookupReaderList(ctx)
+	if r == nil {
+		return C.MDBX_EINVAL
+	}
+	if !r.header {
+		r.header = true
+		if r.err = r.fn("    pid     thread     txnid\n"); r.err != nil {
+			return C.MDBX_EINTR
+		}
+	}
+	txn := "-"
+	if txnid != 0 {
+		txn = strconv.FormatUint(uint64(txnid), 10)
+	}
+	r.err = r.fn(fmt.Sprintf("%10d %10x %10s\n", int(pid), uint64(thread), txn))
+	if r.err != nil {
+		return C.MDBX_EINTR
+	}
+	return success
+}
+
+// ReaderCheck clears stale entries from the reader lock table and returns the
+// number of entries cleared.
+//
+// See mdbx_reader_check.
+func (env *Env) ReaderCheck() (int, error) {
+	var _dead C.int
+	ret := C.mdbx_reader_check(env._env, &_dead)
+	if ret == C.MDBX_RESULT_TRUE {
+		// dead readers were found and cleared
+		ret = success
+	}
+	return int(_dead), operrno("mdbx_reader_check", ret)
+}
+
 // SetMaxReaders sets the maximum number of reader slots in the environment.
 //
 // See mdbx_env_set_maxreaders.
@@ -226,6 +850,30 @@ func (env *Env) View(fn TxnOp) error {
 	return env.run(false, Readonly, fn)
 }
 
+// ViewThread behaves like View but locks the calling goroutine to its thread
+// until the transaction has been terminated.  If env was opened with
+// OpenOptions.TLS the thread is registered for the transaction and
+// unregistered afterwards, unless it was already registered.  ViewThread must
+// be used instead of View in that case, and goroutines created by fn must not
+// use the Txn.  ViewThread cannot be nested, as each thread has a single
+// reader slot.
+//
+// See mdbx_thread_register.
+func (env *Env) ViewThread(fn TxnOp) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	if env.tls {
+		registered, err := env.RegisterThread()
+		if err != nil {
+			return err
+		}
+		if registered {
+			defer env.UnregisterThread()
+		}
+	}
+	return env.run(false, Readonly, fn)
+}
+
 // Update calls fn with a writable transaction.  Update commits the transaction
 // if fn returns a nil error otherwise Update aborts the transaction and
 // returns the error.
@@ -240,8 +888,8 @@ func (env *Env) View(fn TxnOp) error {
 // where it isn't known if runtime.LockOSThread has been called.  In such
 // situations writes must either be done in a newly created goroutine which can
 // be safely locked, or through a worker goroutine that accepts updates to
-// apply and delivers transaction results using channels.  See the package
-// documentation and examples for more details.
+// apply and delivers transaction results using channels, such as a Writer.
+// See the package documentation and examples for more details.
 //
 // Goroutines created by the operation fn must not use methods on the Txn
 // object that fn is passed.  Doing so would have undefined and unpredictable
@@ -253,6 +901,41 @@ func (env *Env) Update(fn TxnOp) error {
 	return env.run(true, 0, fn)
 }
 
+// TryUpdate behaves like Update but does not wait for another write
+// transaction to terminate.  If the environment is locked by another writer
+// TryUpdate returns an error for which IsBusy returns true and fn is not
+// called.
+//
+// See MDBX_TXN_TRY.
+func (env *Env) TryUpdate(fn TxnOp) error {
+	return env.run(true, TxnTry, fn)
+}
+
+// UpdateOptions relax the durability of a single write transaction.  They are
+// meant for low-value writes which can be lost in a system crash without
+// harm.  The transaction is still atomic and consistent.
+type UpdateOptions struct {
+	NoSync     bool // Don't fsync after commit, see TxnNoSync.
+	NoMetaSync bool // Don't fsync the metapage after commit, see TxnNoMetaSync.
+}
+
+func (opts UpdateOptions) flags() uint {
+	var flags uint
+	if opts.NoSync {
+		flags |= TxnNoSync
+	}
+	if opts.NoMetaSync {
+		flags |= TxnNoMetaSync
+	}
+	return flags
+}
+
+// UpdateWithOptions behaves like Update but commits the transaction according
+// to opts.
+func (env *Env) UpdateWithOptions(opts UpdateOptions, fn TxnOp) error {
+	return env.run(true, opts.flags(), fn)
+}
+
 // UpdateLocked behaves like Update but does not lock the calling goroutine to
 // its thread.  UpdateLocked should be used if the calling goroutine is already
 // locked to its thread for another purpose.
@@ -261,8 +944,8 @@ func (env *Env) Update(fn TxnOp) error {
 // where it isn't known if runtime.LockOSThread has been called.  In such
 // situations writes must either be done in a newly created goroutine which can
 // be safely locked, or through a worker goroutine that accepts updates to
-// apply and delivers transaction results using channels.  See the package
-// documentation and examples for more details.
+// apply and delivers transaction results using channels, such as a Writer.
+// See the package documentation and examples for more details.
 //
 // Goroutines created by the operation fn must not use methods on the Txn
 // object that fn is passed.  Doing so would have undefined and unpredictable
@@ -279,6 +962,14 @@ func (env *Env) run(lock bool, flags uint, fn TxnOp) error {
 		runtime.LockOSThread()
 		defer runtime.UnlockOSThread()
 	}
+	err := env.runTxn(flags, fn)
+	if flags&Readonly == 0 && isMapFull(err) && env.growMap(err) {
+		err = env.runTxn(flags, fn)
+	}
+	return err
+}
+
+func (env *Env) runTxn(flags uint, fn TxnOp) error {
 	txn, err := beginTxn(env, nil, flags)
 	if err != nil {
 		return err
@@ -289,9 +980,11 @@ func (env *Env) run(lock bool, flags uint, fn TxnOp) error {
 // CloseDBI closes the database handle, db.  Normally calling CloseDBI
 // explicitly is not necessary.
 //
-// It is the caller's responsibility to serialize calls to CloseDBI.
+// It is the caller's responsibility to serialize calls to CloseDBI.  A handle
+// cached by Env.DBI is forgotten.
 //
 // See mdbx_dbi_close.
 func (env *Env) CloseDBI(db DBI) {
+	env.dbis.forget(db)
 	C.mdbx_dbi_close(env._env, C.MDBX_dbi(db))
 }
@@ -0,0 +1,547 @@
+package mdbx
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// setup opens a new environment in a temporary directory.  The returned
+// function closes the environment and removes the directory.
+func setup(t testing.TB) (*Env, string, func()) {
+	path, err := ioutil.TempDir("", "mdbx_test")
+	if err != nil {
+		t.Fatalf("Cannot create temporary directory: %s", err)
+	}
+	env, err := NewEnv()
+	if err != nil {
+		os.RemoveAll(path)
+		t.Fatalf("Cannot create environment: %s", err)
+	}
+	if err = env.SetMaxDBs(16); err != nil {
+		env.Close()
+		os.RemoveAll(path)
+		t.Fatalf("Cannot setMaxDBs: %s", err)
+	}
+	if err = env.Open(path); err != nil {
+		env.Close()
+		os.RemoveAll(path)
+		t.Fatalf("Cannot open environment: %s", err)
+	}
+	return env, path, func() {
+		env.Close()
+		os.RemoveAll(path)
+	}
+}
+
+func TestEnv_Recovery(t *testing.T) {
+	env, path, teardown := setup(t)
+	defer teardown()
+
+	for _, v := range []string{"v1", "v2", "v3"} {
+		err := env.Update(func(txn *Txn) error {
+			dbi, err := txn.OpenRoot(0)
+			if err != nil {
+				return err
+			}
+			return txn.Put(dbi, []byte("k"), []byte(v), 0)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info, err := env.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	metas := info.MetaPages()
+	if len(metas) != 3 {
+		t.Fatalf("meta pages: %d", len(metas))
+	}
+	if metas[0].Txnid != uint64(info.RecentTxnID) {
+		t.Errorf("most recent meta txnid %d (!= %d)", metas[0].Txnid, info.RecentTxnID)
+	}
+	for _, m := range metas {
+		if !m.Steady() {
+			t.Errorf("meta %d is not steady (sign %d)", m.Target, m.Sign)
+		}
+	}
+	target := metas[1].Target
+	env.Close()
+
+	env, err = NewEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = env.OpenForRecovery(path, target, true); err != nil {
+		env.Close()
+		t.Fatal(err)
+	}
+	err = env.TurnForRecovery(target)
+	env.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env, err = NewEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer env.Close()
+	if err = env.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	err = env.View(func(txn *Txn) error {
+		dbi, err := txn.OpenRoot(0)
+		if err != nil {
+			return err
+		}
+		v, err := txn.Get(dbi, []byte("k"))
+		if err != nil {
+			return err
+		}
+		if string(v) != "v2" {
+			t.Errorf("value after recovery: %q (!= %q)", v, "v2")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEnv_PageWalk(t *testing.T) {
+	env, _, teardown := setup(t)
+	defer teardown()
+
+	err := env.Update(func(txn *Txn) error {
+		dbi, err := txn.CreateDBI("large")
+		if err != nil {
+			return err
+		}
+		return txn.Put(dbi, []byte("k"), make([]byte, 64<<10), 0)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pages := map[string]map[PageType]uint{}
+	err = env.PageWalk(func(page *PageInfo) error {
+		if page.Err != nil {
+			t.Errorf("page %d: %v", page.Pgno, page.Err)
+		}
+		if pages[page.DBI] == nil {
+			pages[page.DBI] = map[PageType]uint{}
+		}
+		pages[page.DBI][page.Type] += page.Number
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := pages[PageWalkMeta][PageMeta]; n != 3 {
+		t.Errorf("meta pages: %d (!= 3)", n)
+	}
+	if n := pages["large"][PageLarge]; n < 2 {
+		t.Errorf("large pages in %q: %d", "large", n)
+	}
+	if n := pages[PageWalkMain][PageLeaf]; n != 1 {
+		t.Errorf("main leaf pages: %d (!= 1)", n)
+	}
+
+	errStop := errors.New("stop")
+	err = env.PageWalk(func(page *PageInfo) error { return errStop })
+	if err != errStop {
+		t.Errorf("unexpected error: %v (!= %v)", err, errStop)
+	}
+}
+
+func TestEnv_TryUpdate(t *testing.T) {
+	env, _, teardown := setup(t)
+	defer teardown()
+
+	locked := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- env.Update(func(txn *Txn) error {
+			close(locked)
+			<-release
+			return nil
+		})
+	}()
+	<-locked
+
+	var called bool
+	err := env.TryUpdate(func(txn *Txn) error {
+		called = true
+		return nil
+	})
+	if !IsBusy(err) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if called {
+		t.Errorf("TryUpdate called fn while the writer is busy")
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	err = env.TryUpdate(func(txn *Txn) error {
+		dbi, err := txn.OpenRoot(0)
+		if err != nil {
+			return err
+		}
+		return txn.Put(dbi, []byte("k"), []byte("v"), 0)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = env.UpdateWithOptions(UpdateOptions{NoSync: true, NoMetaSync: true}, func(txn *Txn) error {
+		dbi, err := txn.OpenRoot(0)
+		if err != nil {
+			return err
+		}
+		return txn.Put(dbi, []byte("k"), []byte("v2"), 0)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := env.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.UnsyncVolume == 0 {
+		t.Errorf("expected unsynced data after a NoSync update")
+	}
+}
+
+func TestEnv_Readahead(t *testing.T) {
+	ram, err := SysRAM()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ram.PageSize <= 0 || ram.Total() <= 0 || ram.Avail() <= 0 || ram.Avail() > ram.Total() {
+		t.Fatalf("unexpected RAM info: %+v", ram)
+	}
+
+	path, err := ioutil.TempDir("", "mdbx_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+
+	env, err := NewEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer env.Close()
+	err = env.OpenWithOptions(path, OpenOptions{Readahead: ReadaheadAuto, ExpectedSize: 1 << 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := env.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode&NoReadahead != 0 {
+		t.Errorf("readahead is off for a small database")
+	}
+	ok, err := env.ReadaheadReasonable(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Errorf("readahead is not reasonable for a small database")
+	}
+	ok, err = env.ReadaheadReasonable(ram.Total() * 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Errorf("readahead is reasonable for a database larger than RAM")
+	}
+}
+
+func TestEnv_ViewThread(t *testing.T) {
+	path, err := ioutil.TempDir("", "mdbx_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+
+	env, err := NewEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer env.Close()
+	if err = env.OpenWithOptions(path, OpenOptions{TLS: true}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := env.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode&NoTLS != 0 {
+		t.Fatalf("environment opened with NoTLS")
+	}
+
+	for i := 0; i < 3; i++ {
+		err = env.ViewThread(func(txn *Txn) error {
+			_, err := txn.OpenRoot(0)
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestEnv_RegisterThread_NoTLS(t *testing.T) {
+	env, _, teardown := setup(t)
+	defer teardown()
+
+	_, err := env.RegisterThread()
+	if !IsErrnoSys(err, syscall.EINVAL) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := env.UnregisterThread(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestEnv_ThreadMismatch(t *testing.T) {
+	env, _, teardown := setup(t)
+	defer teardown()
+
+	began := make(chan *Txn)
+	done := make(chan struct{})
+	aborted := make(chan struct{})
+	go func() {
+		defer close(aborted)
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		txn, err := env.BeginTxn(nil, 0)
+		if err != nil {
+			t.Error(err)
+			close(began)
+			return
+		}
+		began <- txn
+		<-done
+		txn.Abort()
+	}()
+	txn := <-began
+	if txn == nil {
+		return
+	}
+	dbi, err := txn.OpenRoot(0)
+	if err == nil {
+		err = txn.Put(dbi, []byte("k"), []byte("v"), 0)
+	}
+	close(done)
+	<-aborted
+	if !IsThreadMismatch(err) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(err.Error(), "LockOSThread") {
+		t.Errorf("error is not explained: %v", err)
+	}
+}
+
+func TestEnv_SetMapSize(t *testing.T) {
+	env, err := NewEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer env.Close()
+	if err = env.SetMapSize(-1); err != errNegSize {
+		t.Errorf("SetMapSize(-1): %v", err)
+	}
+	const size = 8 << 20
+	if err = env.SetMapSize(size); err != nil {
+		t.Fatal(err)
+	}
+	path, err := ioutil.TempDir("", "mdbx_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+	if err = env.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	info, err := env.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.MapSize != size || info.Geo.Upper != size {
+		t.Errorf("map size %d, upper %d, want %d", info.MapSize, info.Geo.Upper, size)
+	}
+	if info.LastTxnID != info.RecentTxnID {
+		t.Errorf("LastTxnID %d, RecentTxnID %d", info.LastTxnID, info.RecentTxnID)
+	}
+}
+
+func TestEnv_Stat(t *testing.T) {
+	env, _, teardown := setup(t)
+	defer teardown()
+
+	err := env.Update(func(txn *Txn) error {
+		dbi, err := txn.OpenRoot(0)
+		if err != nil {
+			return err
+		}
+		for _, k := range []string{"a", "b", "c"} {
+			if err := txn.Put(dbi, []byte(k), []byte(k), 0); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stat, err := env.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Entries != 3 {
+		t.Errorf("entries %d, want 3", stat.Entries)
+	}
+	info, err := env.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.PSize != info.PageSize {
+		t.Errorf("page size %d, want %d", stat.PSize, info.PageSize)
+	}
+	if stat.Depth != 1 || stat.LeafPages != 1 {
+		t.Errorf("depth %d, leaf pages %d", stat.Depth, stat.LeafPages)
+	}
+}
+
+func TestEnv_Copy(t *testing.T) {
+	env, path, teardown := setup(t)
+	defer teardown()
+
+	err := env.Update(func(txn *Txn) error {
+		dbi, err := txn.OpenRoot(0)
+		if err != nil {
+			return err
+		}
+		return txn.Put(dbi, []byte("k"), []byte("v"), 0)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, flags := range []uint{0, CopyCompact} {
+		dest := filepath.Join(path, "copy"+strconv.Itoa(int(flags)))
+		if err := env.CopyFlag(dest, flags); err != nil {
+			t.Fatalf("flags %d: %v", flags, err)
+		}
+		if err := env.Copy(dest); err == nil {
+			t.Errorf("flags %d: copy to an existing file", flags)
+		}
+
+		cp, err := NewEnv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = cp.Open(dest); err != nil {
+			cp.Close()
+			t.Fatal(err)
+		}
+		err = cp.View(func(txn *Txn) error {
+			dbi, err := txn.OpenRoot(0)
+			if err != nil {
+				return err
+			}
+			v, err := txn.Get(dbi, []byte("k"))
+			if err == nil && !bytes.Equal(v, []byte("v")) {
+				t.Errorf("flags %d: value %q", flags, v)
+			}
+			return err
+		})
+		cp.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestEnv_Flags(t *testing.T) {
+	env, _, teardown := setup(t)
+	defer teardown()
+
+	flags, err := env.Flags()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flags&NoTLS == 0 {
+		t.Errorf("flags %#x, want NoTLS", flags)
+	}
+	if flags&NoSync != 0 {
+		t.Errorf("flags %#x, unexpected NoSync", flags)
+	}
+	if err = env.SetFlags(NoSync); err != nil {
+		t.Fatal(err)
+	}
+	if flags, err = env.Flags(); err != nil || flags&NoSync == 0 {
+		t.Errorf("flags %#x, %v; want NoSync", flags, err)
+	}
+	if err = env.Sync(true); err != nil {
+		t.Error(err)
+	}
+	if err = env.UnsetFlags(NoSync); err != nil {
+		t.Fatal(err)
+	}
+	if flags, err = env.Flags(); err != nil || flags&NoSync != 0 {
+		t.Errorf("flags %#x, %v; unexpected NoSync", flags, err)
+	}
+	if err = env.Sync(false); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEnv_MaxKeySize_ReaderCheck(t *testing.T) {
+	env, _, teardown := setup(t)
+	defer teardown()
+
+	n := env.MaxKeySize()
+	if n <= 0 {
+		t.Fatalf("max key size %d", n)
+	}
+	err := env.Update(func(txn *Txn) error {
+		dbi, err := txn.OpenRoot(0)
+		if err != nil {
+			return err
+		}
+		if err := txn.Put(dbi, make([]byte, n), nil, 0); err != nil {
+			return err
+		}
+		if err := txn.Put(dbi, make([]byte, n+1), nil, 0); !IsErrno(err, BadValSize) {
+			t.Errorf("put of an oversized key: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dead, err := env.ReaderCheck()
+	if err != nil || dead != 0 {
+		t.Errorf("ReaderCheck: %d, %v", dead, err)
+	}
+}
@@ -20,9 +20,18 @@ type OpError struct {
 
 // Error implements the error interface.
 func (err *OpError) Error() string {
+	if err.Errno == ThreadMismatch {
+		return err.Op + ": " + err.Errno.Error() + threadMismatchHint
+	}
 	return err.Op + ": " + err.Errno.Error()
 }
 
+// threadMismatchHint explains the most likely cause of ThreadMismatch errors
+// in Go programs.
+const threadMismatchHint = " (the transaction was used from another OS thread than the one which began it:" +
+	" write transactions, and readonly ones unless the Env uses NoTLS, must be used by a goroutine locked to its thread," +
+	" see runtime.LockOSThread, Env.Update and Env.ViewThread)"
+
 // The most common error codes do not need to be handled explicity.  Errors can
 // be checked through helper functions IsNotFound, IsMapFull, etc, Otherwise
 // they should be checked using the IsErrno function instead of direct
@@ -51,25 +60,33 @@ const (
 	BadTxn          Errno = C.MDBX_BAD_TXN
 	BadValSize      Errno = C.MDBX_BAD_VALSIZE
 	BadDBI          Errno = C.MDBX_BAD_DBI
+	Busy            Errno = C.MDBX_BUSY
+	ThreadMismatch  Errno = C.MDBX_THREAD_MISMATCH
+
+	UnableExtendMapsize Errno = C.MDBX_UNABLE_EXTEND_MAPSIZE
+
+	// MapResized is the LMDB name of UnableExtendMapsize, kept by libmdbx as
+	// the deprecated MDBX_MAP_RESIZED.
+	MapResized = UnableExtendMapsize
 )
 
 // Errno is an error type that represents the (unique) errno values defined by
-// LMDB.  Other errno values (such as EINVAL) are represented with type
-// syscall.Errno.  On Windows, LMDB return codes are translated into portable
+// MDBX.  Other errno values (such as EINVAL) are represented with type
+// syscall.Errno.  On Windows, MDBX return codes are translated into portable
 // syscall.Errno constants (e.g. syscall.EINVAL, syscall.EACCES, etc.).
 //
 // Most often helper functions such as IsNotFound may be used instead of
 // dealing with Errno values directly.
 //
-//		lmdb.IsNotFound(err)
-//		lmdb.IsErrno(err, lmdb.TxnFull)
-//		lmdb.IsErrnoSys(err, syscall.EINVAL)
-//		lmdb.IsErrnoFn(err, os.IsPermission)
+//		mdbx.IsNotFound(err)
+//		mdbx.IsErrno(err, mdbx.TxnFull)
+//		mdbx.IsErrnoSys(err, syscall.EINVAL)
+//		mdbx.IsErrnoFn(err, os.IsPermission)
 type Errno C.int
 
 // minimum and maximum values produced for the Errno type. syscall.Errnos of
 // other values may still be produced.
-const minErrno, maxErrno C.int = C.MDBX_KEYEXIST, 22 //C.MDBX_LAST_ERRCODE
+const minErrno, maxErrno C.int = C.MDBX_KEYEXIST, C.MDBX_LAST_ADDED_ERRCODE
 
 func (e Errno) Error() string {
 	return C.GoString(C.mdbx_strerror(C.int(e)))
@@ -98,6 +115,33 @@ func IsMapFull(err error) bool {
 	return IsErrno(err, MapFull)
 }
 
+// IsMapResized returns true if the environment has grown too large for the
+// current map after being resized by another process, and it could not be
+// mapped again.
+func IsMapResized(err error) bool {
+	return IsErrno(err, MapResized)
+}
+
+// IsBusy returns true if a write transaction could not be started without
+// blocking because another one is running, e.g. after Env.TryUpdate.
+func IsBusy(err error) bool {
+	return IsErrno(err, Busy)
+}
+
+// IsPanic returns true if err was returned by Env.Batch after recovering from
+// a panic in the function it was passed.
+func IsPanic(err error) bool {
+	_, ok := err.(panicked)
+	return ok
+}
+
+// IsThreadMismatch returns true if a transaction was used from another OS
+// thread than the one which began it.  This typically means that a goroutine
+// was not locked to its thread, see Env.Update and Env.ViewThread.
+func IsThreadMismatch(err error) bool {
+	return IsErrno(err, ThreadMismatch)
+}
+
 // IsErrno returns true if err's errno is the given errno.
 func IsErrno(err error, errno Errno) bool {
 	return IsErrnoFn(err, func(err error) bool { return err == errno })
@@ -0,0 +1,313 @@
+/*
+Package index maintains secondary indexes of a database of an mdbx.Env.
+Each index is a DupSort database mapping the keys computed by an index
+function from the items of the primary database to the primary keys of those
+items.  Writes through a Primary update its indexes in the same transaction,
+so that they cannot drift from the primary database.
+
+	users, err := index.Open(env, "users", mdbx.Create)
+	if err != nil {
+		return err
+	}
+	err = users.OpenIndex(env, "users_by_email", func(key, val []byte) [][]byte {
+		return [][]byte{emailOf(val)}
+	})
+	if err != nil {
+		return err
+	}
+	err = env.Update(func(txn *mdbx.Txn) error {
+		return users.Put(txn, id, user, 0)
+	})
+
+Items written to the primary database without going through the Primary are
+not indexed.  Verify reports such drift and Rebuild repairs it.
+*/
+package index
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/xzfkiller/mdbx-go/mdbx"
+)
+
+// ErrNoIndex is returned for an index name which has not been registered.
+var ErrNoIndex = errors.New("index: no such index")
+
+// Func computes the index keys of an item of the primary database.  Func
+// must be deterministic, since the keys of the old value are computed again
+// when an item is overwritten or deleted.  Empty keys are ignored.  key and
+// val are only valid until Func returns, the returned keys may reference them.
+type Func func(key, val []byte) [][]byte
+
+// Primary is a database with secondary indexes.  Register and OpenIndex are
+// not synchronized and must be called before the first write.  The list of
+// indexes is then only read, so writes from many goroutines may go through
+// the same Primary, each in its own transaction.
+type Primary struct {
+	dbi     mdbx.DBI
+	indexes []*secondary
+}
+
+type secondary struct {
+	name string
+	dbi  mdbx.DBI
+	fn   Func
+}
+
+// New returns a Primary for the database dbi.  The database must not have
+// the DupSort flag.
+func New(dbi mdbx.DBI) *Primary {
+	return &Primary{dbi: dbi}
+}
+
+// Open returns a Primary for the named database of env.  Since env.DBI
+// begins a transaction to open it, Open cannot be called from within Update
+// or View, where it would deadlock.
+func Open(env *mdbx.Env, name string, flags uint) (*Primary, error) {
+	dbi, err := env.DBI(name, flags)
+	if err != nil {
+		return nil, err
+	}
+	return New(dbi), nil
+}
+
+// DBI returns the handle of the primary database.
+func (p *Primary) DBI() mdbx.DBI {
+	return p.dbi
+}
+
+// Register adds an index called name, stored in the database dbi which must
+// have been opened with the DupSort flag.  Existing items of the primary
+// database are not indexed, see Rebuild.
+func (p *Primary) Register(name string, dbi mdbx.DBI, fn Func) error {
+	if _, err := p.index(name); err == nil {
+		return fmt.Errorf("index: %q is already registered", name)
+	}
+	p.indexes = append(p.indexes, &secondary{name: name, dbi: dbi, fn: fn})
+	return nil
+}
+
+// OpenIndex opens the named database of env with env.DBI, creating it with
+// the DupSort flag if needed, and registers it as the index called name.  Like
+// Open it begins a transaction of its own.
+func (p *Primary) OpenIndex(env *mdbx.Env, name string, fn Func) error {
+	dbi, err := env.DBI(name, mdbx.Create|mdbx.DupSort)
+	if err != nil {
+		return err
+	}
+	return p.Register(name, dbi, fn)
+}
+
+// IndexDBI returns the handle of the database of the index called name.
+func (p *Primary) IndexDBI(name string) (mdbx.DBI, error) {
+	s, err := p.index(name)
+	if err != nil {
+		return 0, err
+	}
+	return s.dbi, nil
+}
+
+func (p *Primary) index(name string) (*secondary, error) {
+	for _, s := range p.indexes {
+		if s.name == name {
+			return s, nil
+		}
+	}
+	return nil, ErrNoIndex
+}
+
+// get returns a copy of the value of key, or nil if key is not found.
+func (p *Primary) get(txn *mdbx.Txn, key []byte) ([]byte, error) {
+	val, err := txn.GetRaw(p.dbi, key)
+	if mdbx.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{}, val...), nil
+}
+
+// present returns val as the value of an item which exists.  It is never nil,
+// so that an empty value is indexed like any other.
+func present(val []byte) []byte {
+	if val == nil {
+		return []byte{}
+	}
+	return val
+}
+
+// keys returns the distinct non-empty index keys of an item, none if val is
+// nil because the item does not exist.
+func (s *secondary) keys(key, val []byte) map[string]bool {
+	if val == nil {
+		return nil
+	}
+	m := map[string]bool{}
+	for _, k := range s.fn(key, val) {
+		if len(k) > 0 {
+			m[string(k)] = true
+		}
+	}
+	return m
+}
+
+// update replaces the index entries of key computed from old by the ones
+// computed from val.  A nil old or val has no entries, see present.
+func (p *Primary) update(txn *mdbx.Txn, key, old, val []byte) error {
+	for _, s := range p.indexes {
+		before := s.keys(key, old)
+		after := s.keys(key, val)
+		for k := range before {
+			if after[k] {
+				continue
+			}
+			err := txn.DelDup(s.dbi, []byte(k), key)
+			if err != nil && !mdbx.IsNotFound(err) {
+				return err
+			}
+		}
+		for k := range after {
+			if before[k] {
+				continue
+			}
+			err := txn.Put(s.dbi, []byte(k), key, mdbx.NoDupData)
+			if err != nil && !mdbx.IsErrno(err, mdbx.KeyExist) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Put stores val under key in the primary database, passing flags to
+// Txn.Put, and updates the indexes.
+func (p *Primary) Put(txn *mdbx.Txn, key, val []byte, flags uint) error {
+	old, err := p.get(txn, key)
+	if err != nil {
+		return err
+	}
+	if err = txn.Put(p.dbi, key, val, flags); err != nil {
+		return err
+	}
+	return p.update(txn, key, old, present(val))
+}
+
+// Del deletes key from the primary database and its entries from the
+// indexes.  If key is not found Del returns an error for which
+// mdbx.IsNotFound is true.
+func (p *Primary) Del(txn *mdbx.Txn, key []byte) error {
+	old, err := p.get(txn, key)
+	if err != nil {
+		return err
+	}
+	if err = txn.Del(p.dbi, key, nil); err != nil {
+		return err
+	}
+	return p.update(txn, key, old, nil)
+}
+
+// LookupByIndex returns the primary keys of the items having the index key
+// ikey in the index called name, in order.  The slices follow the same
+// RawRead rules as Txn.Get.
+func (p *Primary) LookupByIndex(txn *mdbx.Txn, name string, ikey []byte) ([][]byte, error) {
+	s, err := p.index(name)
+	if err != nil {
+		return nil, err
+	}
+	var keys [][]byte
+	err = txn.ForEachDup(s.dbi, ikey, func(key []byte) (bool, error) {
+		keys = append(keys, key)
+		return true, nil
+	})
+	return keys, err
+}
+
+// entry is an item of an index.
+type entry struct {
+	ikey, key string
+}
+
+// expected returns the entries of the index s computed from the primary
+// database.
+func (p *Primary) expected(txn *mdbx.Txn, s *secondary) (map[entry]bool, error) {
+	m := map[entry]bool{}
+	err := txn.Scan(p.dbi, mdbx.ScanOptions{}, func(k, v []byte) (bool, error) {
+		for ik := range s.keys(k, present(v)) {
+			m[entry{ik, string(k)}] = true
+		}
+		return true, nil
+	})
+	return m, err
+}
+
+// Problem is a difference between an index and the primary database found by
+// Verify.
+type Problem struct {
+	Index    string // Name of the index
+	IndexKey []byte // Index key of the entry
+	Key      []byte // Primary key of the entry
+	Missing  bool   // The entry is missing from the index, or else it is stale
+}
+
+func (pr Problem) String() string {
+	what := "stale"
+	if pr.Missing {
+		what = "missing"
+	}
+	return fmt.Sprintf("%s: %s entry %q -> %q", pr.Index, what, pr.IndexKey, pr.Key)
+}
+
+// Verify compares the contents of the indexes against the primary database
+// and returns the entries which are missing from an index or which no
+// longer match the primary database, ordered by index, index key and primary
+// key.  Verify needs memory for all entries of an index.
+func (p *Primary) Verify(txn *mdbx.Txn) ([]Problem, error) {
+	var problems []Problem
+	for _, s := range p.indexes {
+		want, err := p.expected(txn, s)
+		if err != nil {
+			return nil, err
+		}
+		var found []Problem
+		err = txn.Scan(s.dbi, mdbx.ScanOptions{}, func(ik, k []byte) (bool, error) {
+			e := entry{string(ik), string(k)}
+			if want[e] {
+				delete(want, e)
+			} else {
+				found = append(found, Problem{Index: s.name, IndexKey: []byte(e.ikey), Key: []byte(e.key)})
+			}
+			return true, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		for e := range want {
+			found = append(found, Problem{Index: s.name, IndexKey: []byte(e.ikey), Key: []byte(e.key), Missing: true})
+		}
+		sort.Slice(found, func(i, j int) bool {
+			if c := bytes.Compare(found[i].IndexKey, found[j].IndexKey); c != 0 {
+				return c < 0
+			}
+			return bytes.Compare(found[i].Key, found[j].Key) < 0
+		})
+		problems = append(problems, found...)
+	}
+	return problems, nil
+}
+
+// Rebuild empties the indexes and indexes every item of the primary
+// database again.
+func (p *Primary) Rebuild(txn *mdbx.Txn) error {
+	for _, s := range p.indexes {
+		if err := txn.Drop(s.dbi, false); err != nil {
+			return err
+		}
+	}
+	return txn.Scan(p.dbi, mdbx.ScanOptions{}, func(k, v []byte) (bool, error) {
+		return true, p.update(txn, k, nil, present(v))
+	})
+}
@@ -0,0 +1,251 @@
+package index
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/xzfkiller/mdbx-go/mdbx"
+)
+
+// openEnv opens an environment in a temporary directory, both of which are
+// removed when the test ends.
+func openEnv(t *testing.T) *mdbx.Env {
+	path, err := ioutil.TempDir("", "index_test")
+	if err != nil {
+		t.Fatalf("Cannot create temporary directory: %s", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(path) })
+	env, err := mdbx.NewEnv()
+	if err != nil {
+		t.Fatalf("Cannot create environment: %s", err)
+	}
+	t.Cleanup(func() { env.Close() })
+	if err = env.SetMaxDBs(4); err != nil {
+		t.Fatalf("Cannot set max dbs: %s", err)
+	}
+	if err = env.Open(path); err != nil {
+		t.Fatalf("Cannot open environment: %s", err)
+	}
+	return env
+}
+
+func setup(t *testing.T) (*mdbx.Env, *Primary) {
+	env := openEnv(t)
+
+	// values are "city:tag tag ..."
+	p, err := Open(env, "people", mdbx.Create)
+	if err == nil {
+		err = p.OpenIndex(env, "by_city", func(key, val []byte) [][]byte {
+			return [][]byte{bytes.SplitN(val, []byte(":"), 2)[0]}
+		})
+	}
+	if err == nil {
+		err = p.OpenIndex(env, "by_tag", func(key, val []byte) [][]byte {
+			parts := bytes.SplitN(val, []byte(":"), 2)
+			if len(parts) < 2 {
+				return nil
+			}
+			return bytes.Split(parts[1], []byte(" "))
+		})
+	}
+	if err != nil {
+		t.Fatalf("Cannot open databases: %s", err)
+	}
+	return env, p
+}
+
+func lookup(t *testing.T, txn *mdbx.Txn, p *Primary, name, ikey string) []string {
+	keys, err := p.LookupByIndex(txn, name, []byte(ikey))
+	if err != nil {
+		t.Fatalf("LookupByIndex(%q, %q): %v", name, ikey, err)
+	}
+	var s []string
+	for _, k := range keys {
+		s = append(s, string(k))
+	}
+	return s
+}
+
+func put(txn *mdbx.Txn, p *Primary, items ...string) error {
+	for i := 0; i < len(items); i += 2 {
+		if err := p.Put(txn, []byte(items[i]), []byte(items[i+1]), 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestPrimary(t *testing.T) {
+	env, p := setup(t)
+
+	err := env.Update(func(txn *mdbx.Txn) error {
+		err := put(txn, p,
+			"ann", "oslo:admin dev",
+			"bob", "rome:dev dev",
+			"cid", "oslo:",
+		)
+		if err != nil {
+			return err
+		}
+		if got, want := lookup(t, txn, p, "by_city", "oslo"), []string{"ann", "cid"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("oslo: %q, want %q", got, want)
+		}
+		if got, want := lookup(t, txn, p, "by_tag", "dev"), []string{"ann", "bob"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("dev: %q, want %q", got, want)
+		}
+
+		// overwriting moves the entries
+		if err = put(txn, p, "ann", "rome:dev"); err != nil {
+			return err
+		}
+		if got, want := lookup(t, txn, p, "by_city", "rome"), []string{"ann", "bob"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("rome: %q, want %q", got, want)
+		}
+		if got := lookup(t, txn, p, "by_tag", "admin"); got != nil {
+			t.Errorf("admin: %q", got)
+		}
+
+		// a failed put leaves the indexes alone
+		err = p.Put(txn, []byte("bob"), []byte("oslo:ops"), mdbx.NoOverwrite)
+		if !mdbx.IsErrno(err, mdbx.KeyExist) {
+			t.Errorf("Put with NoOverwrite: %v", err)
+		}
+		if got := lookup(t, txn, p, "by_tag", "ops"); got != nil {
+			t.Errorf("ops: %q", got)
+		}
+
+		if err = p.Del(txn, []byte("bob")); err != nil {
+			return err
+		}
+		if got, want := lookup(t, txn, p, "by_tag", "dev"), []string{"ann"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("dev after Del: %q, want %q", got, want)
+		}
+		if err = p.Del(txn, []byte("bob")); !mdbx.IsNotFound(err) {
+			t.Errorf("Del of a missing key: %v", err)
+		}
+		if _, err = p.LookupByIndex(txn, "by_age", []byte("1")); err != ErrNoIndex {
+			t.Errorf("LookupByIndex of a missing index: %v", err)
+		}
+
+		problems, err := p.Verify(txn)
+		if err != nil {
+			return err
+		}
+		if len(problems) != 0 {
+			t.Errorf("problems: %v", problems)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = p.Register("by_city", p.DBI(), nil); err == nil {
+		t.Error("registered an index twice")
+	}
+}
+
+func TestPrimary_VerifyRebuild(t *testing.T) {
+	env, p := setup(t)
+
+	err := env.Update(func(txn *mdbx.Txn) error {
+		if err := put(txn, p, "ann", "oslo:dev", "bob", "rome:ops"); err != nil {
+			return err
+		}
+		// drift: an item written around the index and a stale entry
+		if err := txn.Put(p.DBI(), []byte("cid"), []byte("oslo:dev"), 0); err != nil {
+			return err
+		}
+		dbi, err := p.IndexDBI("by_tag")
+		if err != nil {
+			return err
+		}
+		if err = txn.Put(dbi, []byte("admin"), []byte("bob"), 0); err != nil {
+			return err
+		}
+
+		problems, err := p.Verify(txn)
+		if err != nil {
+			return err
+		}
+		want := []Problem{
+			{Index: "by_city", IndexKey: []byte("oslo"), Key: []byte("cid"), Missing: true},
+			{Index: "by_tag", IndexKey: []byte("admin"), Key: []byte("bob")},
+			{Index: "by_tag", IndexKey: []byte("dev"), Key: []byte("cid"), Missing: true},
+		}
+		if !reflect.DeepEqual(problems, want) {
+			t.Errorf("problems %v, want %v", problems, want)
+		}
+
+		if err = p.Rebuild(txn); err != nil {
+			return err
+		}
+		if problems, err = p.Verify(txn); err != nil {
+			return err
+		}
+		if len(problems) != 0 {
+			t.Errorf("problems after Rebuild: %v", problems)
+		}
+		if got, want := lookup(t, txn, p, "by_tag", "dev"), []string{"ann", "cid"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("dev: %q, want %q", got, want)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPrimary_emptyValue(t *testing.T) {
+	env, p := setup(t)
+
+	err := p.OpenIndex(env, "by_len", func(key, val []byte) [][]byte {
+		return [][]byte{[]byte(strconv.Itoa(len(val)))}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = env.Update(func(txn *mdbx.Txn) error {
+		if err := p.Put(txn, []byte("dan"), nil, 0); err != nil {
+			return err
+		}
+		if got, want := lookup(t, txn, p, "by_len", "0"), []string{"dan"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("0: %q, want %q", got, want)
+		}
+		problems, err := p.Verify(txn)
+		if err != nil {
+			return err
+		}
+		if len(problems) != 0 {
+			t.Errorf("problems: %v", problems)
+		}
+
+		if err = p.Rebuild(txn); err != nil {
+			return err
+		}
+		if problems, err = p.Verify(txn); err != nil {
+			return err
+		}
+		if len(problems) != 0 {
+			t.Errorf("problems after Rebuild: %v", problems)
+		}
+		if got, want := lookup(t, txn, p, "by_len", "0"), []string{"dan"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("0 after Rebuild: %q, want %q", got, want)
+		}
+
+		if err = p.Del(txn, []byte("dan")); err != nil {
+			return err
+		}
+		if got := lookup(t, txn, p, "by_len", "0"); got != nil {
+			t.Errorf("0 after Del: %q", got)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,334 @@
+/*
+Package keys encodes tuples of values into keys whose bytewise order is the
+order of the tuples, so that composite keys such as (tenant, time descending,
+id) can be scanned with mdbx.Txn.Scan.
+
+	key, err := keys.Pack("acme", keys.Desc(created), uint64(id))
+
+Tuples compare component by component, and a tuple sorts before the longer
+tuples it is a prefix of.  Components of the same type compare by value:
+
+	int, int8, int16, int32, int64          as int64
+	uint, uint8, uint16, uint32, uint64     as uint64
+	float32, float64                        as float64, -0 before +0
+	string, []byte                          bytewise
+	bool                                    false before true
+	time.Time                               chronologically
+
+Components of different types at the same position compare by type, in the
+order of the list above, so signed and unsigned integers do not interleave.
+A component wrapped by Desc sorts in descending order, after the components
+in ascending order, and descending components of different types compare in
+the reverse order of their types.
+
+Every component is encoded without being a prefix of another encoding, which
+is what makes descending components and prefix scans work.  Strings and byte
+slices are escaped so that they may contain any byte.
+*/
+package keys
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/xzfkiller/mdbx-go/mdbx"
+)
+
+// Type tags of components in ascending order.  The tags of descending
+// components are inverted like the rest of their encoding, which sets their
+// high bit.
+const (
+	tagInt    = 0x10
+	tagUint   = 0x11
+	tagFloat  = 0x12
+	tagBytes  = 0x20
+	tagString = 0x21
+	tagFalse  = 0x30
+	tagTrue   = 0x31
+	tagTime   = 0x40
+
+	tagDesc = 0x80
+)
+
+// Strings and byte slices are terminated by 0x00 0x01 and their null bytes
+// are escaped as 0x00 0xff.
+const (
+	escape     = 0x00
+	escapedNul = 0xff
+	terminator = 0x01
+)
+
+// ErrInvalid is returned by Unpack for bytes which are not a packed tuple.
+var ErrInvalid = errors.New("keys: invalid tuple encoding")
+
+// Tuple is a list of components.
+type Tuple []interface{}
+
+// desc marks a component to be sorted in descending order.
+type desc struct {
+	v interface{}
+}
+
+// Desc wraps a component to be sorted in descending order.  Unpack returns
+// the value of descending components without the wrapper.
+func Desc(v interface{}) interface{} {
+	return desc{v}
+}
+
+// Pack encodes components into a key.  Pack returns an error if a component
+// has an unsupported type.
+func Pack(components ...interface{}) ([]byte, error) {
+	return Tuple(components).Pack()
+}
+
+// MustPack is like Pack but panics on error.  It simplifies packing keys of
+// known types.
+func MustPack(components ...interface{}) []byte {
+	b, err := Pack(components...)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// Pack encodes t into a key.
+func (t Tuple) Pack() ([]byte, error) {
+	return t.AppendPack(nil)
+}
+
+// AppendPack appends the encoding of t to b and returns the extended slice.
+func (t Tuple) AppendPack(b []byte) ([]byte, error) {
+	var err error
+	for i, v := range t {
+		b, err = appendComponent(b, v)
+		if err != nil {
+			return nil, fmt.Errorf("keys: component %d: %v", i, err)
+		}
+	}
+	return b, nil
+}
+
+func appendComponent(b []byte, v interface{}) ([]byte, error) {
+	if d, ok := v.(desc); ok {
+		if _, ok := d.v.(desc); ok {
+			return nil, errors.New("nested Desc")
+		}
+		start := len(b)
+		b, err := appendComponent(b, d.v)
+		if err != nil {
+			return nil, err
+		}
+		for i := start; i < len(b); i++ {
+			b[i] = ^b[i]
+		}
+		return b, nil
+	}
+
+	switch v := v.(type) {
+	case int:
+		return appendInt(b, int64(v)), nil
+	case int8:
+		return appendInt(b, int64(v)), nil
+	case int16:
+		return appendInt(b, int64(v)), nil
+	case int32:
+		return appendInt(b, int64(v)), nil
+	case int64:
+		return appendInt(b, v), nil
+	case uint:
+		return appendUint(b, tagUint, uint64(v)), nil
+	case uint8:
+		return appendUint(b, tagUint, uint64(v)), nil
+	case uint16:
+		return appendUint(b, tagUint, uint64(v)), nil
+	case uint32:
+		return appendUint(b, tagUint, uint64(v)), nil
+	case uint64:
+		return appendUint(b, tagUint, v), nil
+	case float32:
+		return appendFloat(b, float64(v)), nil
+	case float64:
+		return appendFloat(b, v), nil
+	case []byte:
+		return appendBytes(b, tagBytes, v), nil
+	case string:
+		return appendBytes(b, tagString, []byte(v)), nil
+	case bool:
+		if v {
+			return append(b, tagTrue), nil
+		}
+		return append(b, tagFalse), nil
+	case time.Time:
+		b = appendUint(b, tagTime, uint64(v.Unix())^1<<63)
+		return appendUint32(b, uint32(v.Nanosecond())), nil
+	}
+	return nil, fmt.Errorf("unsupported type %T", v)
+}
+
+func appendInt(b []byte, v int64) []byte {
+	// flipping the sign bit orders negative numbers before positive ones
+	return appendUint(b, tagInt, uint64(v)^1<<63)
+}
+
+func appendUint(b []byte, tag byte, v uint64) []byte {
+	var buf [9]byte
+	buf[0] = tag
+	binary.BigEndian.PutUint64(buf[1:], v)
+	return append(b, buf[:]...)
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+func appendFloat(b []byte, v float64) []byte {
+	// positive numbers sort after negative ones by setting the sign bit, the
+	// order of negative numbers is reversed by inverting all bits
+	bits := math.Float64bits(v)
+	if bits&(1<<63) != 0 {
+		bits = ^bits
+	} else {
+		bits |= 1 << 63
+	}
+	return appendUint(b, tagFloat, bits)
+}
+
+func appendBytes(b []byte, tag byte, v []byte) []byte {
+	b = append(b, tag)
+	for _, c := range v {
+		b = append(b, c)
+		if c == escape {
+			b = append(b, escapedNul)
+		}
+	}
+	return append(b, escape, terminator)
+}
+
+// Unpack decodes a key encoded by Pack.  Integers are decoded as int64 or
+// uint64, floats as float64 and times as time.Time in UTC.  Unpack returns
+// ErrInvalid if key is not a valid encoding.
+func Unpack(key []byte) (Tuple, error) {
+	var t Tuple
+	for len(key) > 0 {
+		v, n, err := decodeComponent(key)
+		if err != nil {
+			return nil, err
+		}
+		t = append(t, v)
+		key = key[n:]
+	}
+	return t, nil
+}
+
+// decodeComponent decodes the component at the start of b and returns its
+// encoded length.
+func decodeComponent(b []byte) (interface{}, int, error) {
+	var mask byte
+	if b[0]&tagDesc != 0 {
+		mask = 0xff
+	}
+	at := func(i int) byte { return b[i] ^ mask }
+	fixed := func(n int) ([]byte, error) {
+		if len(b) < 1+n {
+			return nil, ErrInvalid
+		}
+		p := make([]byte, n)
+		for i := range p {
+			p[i] = at(1 + i)
+		}
+		return p, nil
+	}
+
+	switch tag := at(0); tag {
+	case tagInt, tagUint, tagFloat:
+		p, err := fixed(8)
+		if err != nil {
+			return nil, 0, err
+		}
+		u := binary.BigEndian.Uint64(p)
+		switch tag {
+		case tagInt:
+			return int64(u ^ 1<<63), 9, nil
+		case tagUint:
+			return u, 9, nil
+		}
+		if u&(1<<63) != 0 {
+			u &^= 1 << 63
+		} else {
+			u = ^u
+		}
+		return math.Float64frombits(u), 9, nil
+	case tagTime:
+		p, err := fixed(12)
+		if err != nil {
+			return nil, 0, err
+		}
+		sec := int64(binary.BigEndian.Uint64(p) ^ 1<<63)
+		nsec := int64(binary.BigEndian.Uint32(p[8:]))
+		return time.Unix(sec, nsec).UTC(), 13, nil
+	case tagFalse:
+		return false, 1, nil
+	case tagTrue:
+		return true, 1, nil
+	case tagBytes, tagString:
+		var p []byte
+		for i := 1; i+1 < len(b); i++ {
+			c := at(i)
+			if c != escape {
+				p = append(p, c)
+				continue
+			}
+			switch at(i + 1) {
+			case escapedNul:
+				p = append(p, escape)
+				i++
+			case terminator:
+				if tag == tagString {
+					return string(p), i + 2, nil
+				}
+				if p == nil {
+					p = []byte{}
+				}
+				return p, i + 2, nil
+			default:
+				return nil, 0, ErrInvalid
+			}
+		}
+	}
+	return nil, 0, ErrInvalid
+}
+
+// PrefixScan returns the options of mdbx.Txn.Scan which select the keys of
+// the tuples starting with the given components.
+func PrefixScan(components ...interface{}) (mdbx.ScanOptions, error) {
+	prefix, err := Pack(components...)
+	if err != nil {
+		return mdbx.ScanOptions{}, err
+	}
+	return mdbx.ScanOptions{Prefix: prefix}, nil
+}
+
+// RangeScan returns the options of mdbx.Txn.Scan which select the keys of
+// the tuples from start, inclusive, to end, exclusive.  Tuples which extend
+// end, i.e. have end as a prefix, are excluded as well.  A nil start or end
+// leaves that side of the range open.
+func RangeScan(start, end Tuple) (mdbx.ScanOptions, error) {
+	var opts mdbx.ScanOptions
+	var err error
+	if start != nil {
+		if opts.Start, err = start.Pack(); err != nil {
+			return opts, err
+		}
+	}
+	if end != nil {
+		if opts.End, err = end.Pack(); err != nil {
+			return opts, err
+		}
+	}
+	return opts, nil
+}
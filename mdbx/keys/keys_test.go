@@ -0,0 +1,289 @@
+package keys
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"reflect"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"github.com/xzfkiller/mdbx-go/mdbx"
+)
+
+// randTuple generates tuples from small pools of values of every type, so
+// that generated tuples often share prefixes.
+type randTuple Tuple
+
+func (randTuple) Generate(rng *rand.Rand, size int) reflect.Value {
+	t := make(randTuple, rng.Intn(4))
+	for i := range t {
+		t[i] = randComponent(rng)
+	}
+	return reflect.ValueOf(t)
+}
+
+func randComponent(rng *rand.Rand) interface{} {
+	var v interface{}
+	switch rng.Intn(7) {
+	case 0:
+		ints := []int64{math.MinInt64, -1 << 40, -256, -1, 0, 1, 255, 1 << 40, math.MaxInt64}
+		v = ints[rng.Intn(len(ints))] + int64(rng.Intn(3)-1)
+	case 1:
+		uints := []uint64{0, 1, 255, 256, 1 << 40, math.MaxUint64 - 1}
+		v = uints[rng.Intn(len(uints))] + uint64(rng.Intn(2))
+	case 2:
+		floats := []float64{math.Inf(-1), -1e300, -1.5, -math.SmallestNonzeroFloat64, math.Copysign(0, -1),
+			0, math.SmallestNonzeroFloat64, 0.1, 1.5, 1e300, math.Inf(1)}
+		v = floats[rng.Intn(len(floats))]
+	case 3, 4:
+		p := make([]byte, rng.Intn(4))
+		for i := range p {
+			p[i] = []byte{0x00, 0x01, 'a', 0xfe, 0xff}[rng.Intn(5)]
+		}
+		if rng.Intn(2) == 0 {
+			v = string(p)
+		} else {
+			v = p
+		}
+	case 5:
+		v = rng.Intn(2) == 0
+	default:
+		secs := []int64{-1 << 40, -1, 0, 1, 1600000000}
+		v = time.Unix(secs[rng.Intn(len(secs))], int64(rng.Intn(3))*int64(time.Second/3)).UTC()
+	}
+	if rng.Intn(4) == 0 {
+		return Desc(v)
+	}
+	return v
+}
+
+// rank returns the rank of the type of a component and its value.
+func rank(v interface{}) (int, interface{}) {
+	if d, ok := v.(desc); ok {
+		r, v := rank(d.v)
+		return 200 - r, v
+	}
+	switch v := v.(type) {
+	case int64:
+		return 0, v
+	case uint64:
+		return 1, v
+	case float64:
+		return 2, v
+	case []byte:
+		return 3, v
+	case string:
+		return 4, v
+	case bool:
+		return 5, v
+	case time.Time:
+		return 6, v
+	}
+	panic("unexpected type")
+}
+
+// compareComponents is the logical order of components.
+func compareComponents(a, b interface{}) int {
+	ra, va := rank(a)
+	rb, vb := rank(b)
+	if ra != rb {
+		return ra - rb
+	}
+	c := 0
+	switch va := va.(type) {
+	case int64:
+		c = cmpOrdered(va < vb.(int64), va > vb.(int64))
+	case uint64:
+		c = cmpOrdered(va < vb.(uint64), va > vb.(uint64))
+	case float64:
+		fb := vb.(float64)
+		c = cmpOrdered(va < fb || va == fb && math.Signbit(va) && !math.Signbit(fb),
+			va > fb || va == fb && !math.Signbit(va) && math.Signbit(fb))
+	case []byte:
+		c = bytes.Compare(va, vb.([]byte))
+	case string:
+		c = cmpOrdered(va < vb.(string), va > vb.(string))
+	case bool:
+		c = cmpOrdered(!va && vb.(bool), va && !vb.(bool))
+	case time.Time:
+		c = cmpOrdered(va.Before(vb.(time.Time)), va.After(vb.(time.Time)))
+	}
+	if ra >= 100 {
+		return -c
+	}
+	return c
+}
+
+func cmpOrdered(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
+
+func compareTuples(a, b randTuple) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := compareComponents(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return len(a) - len(b)
+}
+
+func sign(c int) int {
+	return cmpOrdered(c < 0, c > 0)
+}
+
+func TestPack_Order(t *testing.T) {
+	prop := func(a, b randTuple) bool {
+		ka, err := Tuple(a).Pack()
+		if err != nil {
+			t.Fatal(err)
+		}
+		kb, err := Tuple(b).Pack()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sign(bytes.Compare(ka, kb)) != sign(compareTuples(a, b)) {
+			t.Logf("%#v\n%#v\n%x\n%x", a, b, ka, kb)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(prop, &quick.Config{MaxCount: 20000}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPack_Prefix(t *testing.T) {
+	prop := func(a, b randTuple) bool {
+		prefix := append(Tuple(nil), a...)
+		ka := MustPack(prefix...)
+		kab := MustPack(append(prefix, b...)...)
+		return bytes.HasPrefix(kab, ka)
+	}
+	if err := quick.Check(prop, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUnpack(t *testing.T) {
+	prop := func(a randTuple) bool {
+		k := MustPack(a...)
+		got, err := Unpack(k)
+		if err != nil {
+			t.Logf("%#v: %v", a, err)
+			return false
+		}
+		want := Tuple(nil)
+		for _, v := range a {
+			if d, ok := v.(desc); ok {
+				v = d.v
+			}
+			want = append(want, v)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Logf("%#v\n%#v", got, want)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(prop, &quick.Config{MaxCount: 5000}); err != nil {
+		t.Error(err)
+	}
+
+	got, err := Unpack(MustPack(int8(-3), uint16(7), float32(1.5), Desc("x")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Tuple{int64(-3), uint64(7), 1.5, "x"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("unpacked %#v, want %#v", got, want)
+	}
+	for _, k := range [][]byte{{0x05}, {tagInt, 1, 2}, {tagString, 'a'}, {tagString, 0x00, 0x02}} {
+		if _, err := Unpack(k); err != ErrInvalid {
+			t.Errorf("Unpack(%x): %v", k, err)
+		}
+	}
+	if _, err := Pack(struct{}{}); err == nil {
+		t.Error("packed a struct")
+	}
+	if _, err := Pack(Desc(Desc(1))); err == nil {
+		t.Error("packed a nested Desc")
+	}
+}
+
+func TestPrefixScan(t *testing.T) {
+	path, err := ioutil.TempDir("", "keys_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+	env, err := mdbx.NewEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer env.Close()
+	if err = env.Open(path); err != nil {
+		t.Fatal(err)
+	}
+
+	day := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+	err = env.Update(func(txn *mdbx.Txn) error {
+		dbi, err := txn.OpenRoot(0)
+		if err != nil {
+			return err
+		}
+		for _, tenant := range []string{"acme", "acme\x00", "acmf"} {
+			for h := 0; h < 3; h++ {
+				k := MustPack(tenant, Desc(day.Add(time.Duration(h)*time.Hour)), uint64(h))
+				if err := txn.Put(dbi, k, []byte(tenant), 0); err != nil {
+					return err
+				}
+			}
+		}
+
+		opts, err := PrefixScan("acme")
+		if err != nil {
+			return err
+		}
+		var hours []uint64
+		err = txn.Scan(dbi, opts, func(k, v []byte) (bool, error) {
+			t, err := Unpack(k)
+			if err != nil {
+				return false, err
+			}
+			hours = append(hours, t[2].(uint64))
+			return true, nil
+		})
+		if err != nil {
+			return err
+		}
+		if want := []uint64{2, 1, 0}; !reflect.DeepEqual(hours, want) {
+			t.Errorf("hours %v, want %v", hours, want)
+		}
+
+		opts, err = RangeScan(Tuple{"acme", Desc(day.Add(time.Hour))}, Tuple{"acmf"})
+		if err != nil {
+			return err
+		}
+		var n int
+		err = txn.Scan(dbi, opts, func(k, v []byte) (bool, error) {
+			n++
+			return true, nil
+		})
+		if n != 5 {
+			t.Errorf("range scan: %d items, want 5", n)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -2,15 +2,15 @@ package mdbx
 
 /*
 #cgo CFLAGS: -pthread -W -Wall -Wno-unused-parameter -Wno-format-extra-args -Wbad-function-cast -Wno-missing-field-initializers -O2 -g
-#cgo LDFLAGS: -L../lib -lmdbx -Wl,-rpath=../lib
+#cgo !system_libmdbx CFLAGS: -I${SRCDIR}/mdbxdist -std=gnu11
+#cgo !system_libmdbx,linux LDFLAGS: -lrt
+#cgo !system_libmdbx,windows LDFLAGS: -lntdll
+#cgo system_libmdbx LDFLAGS: -lmdbx
 
 #include "mdbx.h"
 */
 import "C"
 
 func cbool(b bool) C.bool {
-	if b {
-		return C.true
-	}
-	return C.false
+	return C.bool(b)
 }
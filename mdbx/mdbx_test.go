@@ -101,3 +101,26 @@ func TestMDBX(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestVersion(t *testing.T) {
+	if err := CheckVersion(); err != nil {
+		t.Fatal(err)
+	}
+	v := Version()
+	if v.Major != VersionMajor || v.Minor != VersionMinor {
+		t.Errorf("version %s (!= %d.%d)", v, VersionMajor, VersionMinor)
+	}
+	if v.Git.Describe == "" {
+		t.Errorf("empty git describe")
+	}
+	t.Logf("libmdbx %s (%s)", v, v.Git.Describe)
+	if s := VersionString(); s == "" {
+		t.Errorf("empty version string")
+	}
+
+	b := BuildInfo()
+	if b.Target == "" {
+		t.Errorf("empty build target")
+	}
+	t.Logf("built %s for %s by %s with %q", b.Datetime, b.Target, b.Compiler, b.Flags)
+}
@@ -0,0 +1,28 @@
+//go:build !system_libmdbx
+// +build !system_libmdbx
+
+/* mdbxdist.c
+ * Compiles the vendored amalgamated libmdbx source into the package.  Build
+ * with the system_libmdbx tag to link a libmdbx installed in the system
+ * instead.  The defaults below may be overridden through CGO_CFLAGS.
+ * */
+#ifndef MDBX_DEBUG
+#define NDEBUG
+#define MDBX_DEBUG 0
+#endif
+#ifndef MDBX_TXN_CHECKOWNER
+#define MDBX_TXN_CHECKOWNER 1
+#endif
+#ifndef MDBX_ENABLE_PGOP_STAT
+#define MDBX_ENABLE_PGOP_STAT 1
+#endif
+#ifndef MDBX_BUILD_FLAGS
+#define MDBX_BUILD_FLAGS "cgo"
+#endif
+
+#if defined(__GNUC__) && !defined(__clang__) && __GNUC__ >= 7
+/* GCC reports a false positive for the atomic stores to the reader table. */
+#pragma GCC diagnostic ignored "-Wstringop-overflow"
+#endif
+
+#include "mdbxdist/mdbx.c"
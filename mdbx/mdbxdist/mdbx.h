@@ -365,8 +365,7 @@ typedef mode_t mdbx_mode_t;
 
 #ifndef __cplusplus
 #ifndef bool
-//#define bool _Bool
-#define bool int
+#define bool _Bool
 #endif
 #ifndef true
 #define true (1)
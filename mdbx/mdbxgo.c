@@ -15,10 +15,27 @@ int mdbxgo_mdb_del1(MDBX_txn *txn, MDBX_dbi dbi, char *kdata, size_t kn) {
 }
 
 int mdbxgo_mdb_del2(MDBX_txn *txn, MDBX_dbi dbi, char *kdata, size_t kn, char *vdata, size_t vn) {
-    MDBX_val key, val;
+    MDBX_val key, val, found;
+    MDBX_cursor *cur;
+    int ret;
     MDBXGO_SET_VAL(&key, kn, kdata);
     MDBXGO_SET_VAL(&val, vn, vdata);
-    return mdbx_del(txn, dbi, &key, &val);
+    ret = mdbx_cursor_open(txn, dbi, &cur);
+    if (ret != MDBX_SUCCESS)
+        return ret;
+    found = val;
+    ret = mdbx_cursor_get(cur, &key, &found, MDBX_GET_BOTH);
+    if (ret == MDBX_INCOMPATIBLE) {
+        /* not a DupSort database, mdbx_del compares the value */
+        mdbx_cursor_close(cur);
+        return mdbx_del(txn, dbi, &key, &val);
+    }
+    if (ret == MDBX_SUCCESS && mdbx_dcmp(txn, dbi, &val, &found) != 0)
+        ret = MDBX_NOTFOUND;
+    if (ret == MDBX_SUCCESS)
+        ret = mdbx_cursor_del(cur, 0);
+    mdbx_cursor_close(cur);
+    return ret;
 }
 
 int mdbxgo_mdb_get(MDBX_txn *txn, MDBX_dbi dbi, char *kdata, size_t kn, MDBX_val *val) {
@@ -40,3 +57,141 @@ int mdbxgo_mdb_put2(MDBX_txn *txn, MDBX_dbi dbi, char *kdata, size_t kn, char *v
     return mdbx_put(txn, dbi, &key, &val, flags);
 }
 
+int mdbxgo_mdb_cursor_get1(MDBX_cursor *cur, char *kdata, size_t kn, MDBX_val *key, MDBX_val *val, MDBX_cursor_op op) {
+    MDBXGO_SET_VAL(key, kn, kdata);
+    return mdbx_cursor_get(cur, key, val, op);
+}
+
+int mdbxgo_mdb_cursor_get2(MDBX_cursor *cur, char *kdata, size_t kn, char *vdata, size_t vn, MDBX_val *key, MDBX_val *val, MDBX_cursor_op op) {
+    MDBXGO_SET_VAL(key, kn, kdata);
+    MDBXGO_SET_VAL(val, vn, vdata);
+    return mdbx_cursor_get(cur, key, val, op);
+}
+
+int mdbxgo_mdb_cursor_put2(MDBX_cursor *cur, char *kdata, size_t kn, char *vdata, size_t vn, unsigned int flags) {
+    MDBX_val key, val;
+    MDBXGO_SET_VAL(&key, kn, kdata);
+    MDBXGO_SET_VAL(&val, vn, vdata);
+    return mdbx_cursor_put(cur, &key, &val, flags);
+}
+
+int mdbxgo_mdb_cursor_put1(MDBX_cursor *cur, char *kdata, size_t kn, MDBX_val *val, unsigned int flags) {
+    MDBX_val key;
+    MDBXGO_SET_VAL(&key, kn, kdata);
+    return mdbx_cursor_put(cur, &key, val, flags);
+}
+
+int mdbxgo_mdb_cursor_putmulti(MDBX_cursor *cur, char *kdata, size_t kn, char *vdata, size_t vn, size_t vstride, unsigned int flags) {
+    MDBX_val key, val[2];
+    size_t count = 0;
+    MDBXGO_SET_VAL(&key, kn, kdata);
+    // put single values until the key holds two of them, see mdbxgo.h.
+    while (vn > 0 && count < 2) {
+        MDBXGO_SET_VAL(&val[0], vstride, vdata);
+        int ret = mdbx_cursor_put(cur, &key, &val[0], flags);
+        if (ret != MDBX_SUCCESS)
+            return ret;
+        ret = mdbx_cursor_count(cur, &count);
+        if (ret != MDBX_SUCCESS)
+            return ret;
+        vdata += vstride;
+        vn -= vstride;
+    }
+    if (vn == 0)
+        return MDBX_SUCCESS;
+    // MDBX_MULTIPLE takes the stride in val[0] and the number of values in
+    // val[1], and rejects a single value.
+    MDBXGO_SET_VAL(&val[0], vstride, vdata);
+    if (vn == vstride)
+        return mdbx_cursor_put(cur, &key, &val[0], flags);
+    MDBXGO_SET_VAL(&val[1], vn / vstride, NULL);
+    return mdbx_cursor_put(cur, &key, val, flags | MDBX_MULTIPLE);
+}
+
+void mdbxgo_mdb_get_many(MDBX_txn *txn, MDBX_dbi dbi, char *kdata, size_t *koffs, size_t n, MDBX_val *vals, int *rets) {
+    MDBX_val key;
+    size_t off = 0;
+    for (size_t i = 0; i < n; i++) {
+        MDBXGO_SET_VAL(&key, koffs[i] - off, kdata + off);
+        rets[i] = mdbx_get(txn, dbi, &key, &vals[i]);
+        off = koffs[i];
+    }
+}
+
+size_t mdbxgo_mdb_put_many(MDBX_txn *txn, MDBX_dbi dbi, char *data, size_t *offs, size_t n, unsigned int flags, int *rets) {
+    MDBX_val key, val;
+    MDBX_put_flags_t put = (MDBX_put_flags_t)(flags & ~MDBXGO_STOP_ON_ERROR);
+    size_t off = 0;
+    for (size_t i = 0; i < n; i++) {
+        MDBXGO_SET_VAL(&key, offs[2*i] - off, data + off);
+        off = offs[2*i];
+        MDBXGO_SET_VAL(&val, offs[2*i+1] - off, data + off);
+        off = offs[2*i+1];
+        rets[i] = mdbx_put(txn, dbi, &key, &val, put);
+        if (rets[i] != MDBX_SUCCESS && (flags & MDBXGO_STOP_ON_ERROR))
+            return i + 1;
+    }
+    return n;
+}
+
+size_t mdbxgo_mdb_del_many(MDBX_txn *txn, MDBX_dbi dbi, char *data, size_t *offs, size_t n, unsigned int flags, int *rets) {
+    MDBX_val key;
+    size_t off = 0;
+    for (size_t i = 0; i < n; i++) {
+        MDBXGO_SET_VAL(&key, offs[i] - off, data + off);
+        off = offs[i];
+        rets[i] = mdbx_del(txn, dbi, &key, NULL);
+        if (rets[i] != MDBX_SUCCESS && (flags & MDBXGO_STOP_ON_ERROR))
+            return i + 1;
+    }
+    return n;
+}
+
+static int mdbxgo_pgvisitor_proxy(const uint64_t pgno, const unsigned number, void *const ctx, const int deep,
+                                  const char *const dbi, const size_t page_size, const MDBX_page_type_t type,
+                                  const MDBX_error_t err, const size_t nentries, const size_t payload_bytes,
+                                  const size_t header_bytes, const size_t unused_bytes) {
+    // wrap dbi and call the bridge function exported from pgwalk.go.
+    mdbxgo_ConstCString s;
+    int kind = MDBXGO_PGWALK_NAMED;
+    s.p = NULL;
+    if (dbi == MDBX_PGWALK_MAIN)
+        kind = MDBXGO_PGWALK_MAIN;
+    else if (dbi == MDBX_PGWALK_GC)
+        kind = MDBXGO_PGWALK_GC;
+    else if (dbi == MDBX_PGWALK_META)
+        kind = MDBXGO_PGWALK_META;
+    else
+        s.p = dbi;
+    return mdbxgoPgVisitorBridge((size_t)ctx, pgno, number, deep, kind, s, page_size, type, err,
+                                 nentries, payload_bytes, header_bytes, unused_bytes);
+}
+
+int mdbxgo_env_pgwalk(MDBX_txn *txn, size_t ctx, int dont_check_keys_ordering) {
+    return mdbx_env_pgwalk(txn, &mdbxgo_pgvisitor_proxy, (void *)ctx, dont_check_keys_ordering);
+}
+
+static int mdbxgo_reader_list_proxy(void *ctx, int num, int slot, mdbx_pid_t pid, mdbx_tid_t thread, uint64_t txnid,
+                                    uint64_t lag, size_t bytes_used, size_t bytes_retained) {
+    // call the bridge function exported from env.go.
+    return mdbxgoReaderListBridge((size_t)ctx, (int)pid, (uint64_t)thread, txnid);
+}
+
+int mdbxgo_reader_list(MDBX_env *env, size_t ctx) {
+    return mdbx_reader_list(env, &mdbxgo_reader_list_proxy, (void *)ctx);
+}
+
+#pragma GCC diagnostic push
+#pragma GCC diagnostic ignored "-Wdeprecated-declarations"
+int mdbxgo_txn_straggler(const MDBX_txn *txn, int *lag, int *percent) {
+    int rc = mdbx_txn_straggler(txn, percent);
+    if (rc >= 0) {
+        *lag = rc;
+        return MDBX_SUCCESS;
+    }
+    // system error codes are returned negated, unlike the MDBX ones.
+    if (rc < MDBX_FIRST_LMDB_ERRCODE || rc > MDBX_LAST_ADDED_ERRCODE)
+        return -rc;
+    return rc;
+}
+#pragma GCC diagnostic pop
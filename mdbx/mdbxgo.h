@@ -18,10 +18,57 @@
  *      https://github.com/bmatsuo/lmdb-go/issues/63
  * */
 int mdbxgo_mdb_del1(MDBX_txn *txn, MDBX_dbi dbi, char *kdata, size_t kn);
+/* mdbxgo_mdb_del2 deletes the item of key with an equal value.  In libmdbx
+ * 0.10.1 mdbx_del with a value looks it up with MDBX_GET_BOTH, which in a
+ * DupSort database positions at the first value not less than the given one,
+ * so that a missing value deletes the next greater one.  The value found by a
+ * cursor is compared before it is deleted instead.
+ * */
 int mdbxgo_mdb_del2(MDBX_txn *txn, MDBX_dbi dbi, char *kdata, size_t kn, char *vdata, size_t vn);
 int mdbxgo_mdb_get(MDBX_txn *txn, MDBX_dbi dbi, char *kdata, size_t kn, MDBX_val *val);
 int mdbxgo_mdb_put1(MDBX_txn *txn, MDBX_dbi dbi, char *kdata, size_t kn, MDBX_val *val, unsigned int flags);
 int mdbxgo_mdb_put2(MDBX_txn *txn, MDBX_dbi dbi, char *kdata, size_t kn, char *vdata, size_t vn, unsigned int flags);
+int mdbxgo_mdb_cursor_get1(MDBX_cursor *cur, char *kdata, size_t kn, MDBX_val *key, MDBX_val *val, MDBX_cursor_op op);
+int mdbxgo_mdb_cursor_get2(MDBX_cursor *cur, char *kdata, size_t kn, char *vdata, size_t vn, MDBX_val *key, MDBX_val *val, MDBX_cursor_op op);
+int mdbxgo_mdb_cursor_put2(MDBX_cursor *cur, char *kdata, size_t kn, char *vdata, size_t vn, unsigned int flags);
+int mdbxgo_mdb_cursor_put1(MDBX_cursor *cur, char *kdata, size_t kn, MDBX_val *val, unsigned int flags);
+
+/* mdbxgo_mdb_cursor_putmulti stores the vn/vstride values of vstride bytes
+ * packed into vdata under one key using MDBX_MULTIPLE.  In libmdbx 0.10.1 an
+ * MDBX_MULTIPLE put which turns a single value into a sub-page goes on to
+ * store the converted value again with an empty key, failing with
+ * MDBX_BAD_VALSIZE (dupdata_flag is not reset before "goto more" in
+ * mdbx_cursor_put).  So values are put one at a time until the key holds two
+ * of them, and the rest with a single MDBX_MULTIPLE put.  It stops at the
+ * first failure.
+ * */
+int mdbxgo_mdb_cursor_putmulti(MDBX_cursor *cur, char *kdata, size_t kn, char *vdata, size_t vn, size_t vstride, unsigned int flags);
+
+/* Batch functions for mdbx get operations.  Keys are packed into kdata, with
+ * koffs holding the offset just past the end of each key.  The value and
+ * result code for the i-th key are stored in vals[i] and rets[i], so that
+ * the whole batch costs a single cgo call.
+ * */
+void mdbxgo_mdb_get_many(MDBX_txn *txn, MDBX_dbi dbi, char *kdata, size_t *koffs, size_t n, MDBX_val *vals, int *rets);
+
+/* MDBXGO_STOP_ON_ERROR is a flag for the batch put and del functions which is
+ * not passed to mdbx.  It stops the batch at the first item which fails.
+ * */
+#define MDBXGO_STOP_ON_ERROR 0x80000000u
+
+/* Batch functions for mdbx put and del operations.  Items are packed into
+ * data, with offs holding the offset just past the end of each key (and of
+ * each value for put, so offs has 2*n entries).  The result code for the i-th
+ * item is stored in rets[i] and the number of items attempted is returned.
+ * */
+size_t mdbxgo_mdb_put_many(MDBX_txn *txn, MDBX_dbi dbi, char *data, size_t *offs, size_t n, unsigned int flags, int *rets);
+size_t mdbxgo_mdb_del_many(MDBX_txn *txn, MDBX_dbi dbi, char *data, size_t *offs, size_t n, unsigned int flags, int *rets);
+
+/* mdbxgo_txn_straggler calls the deprecated mdbx_txn_straggler without causing
+ * warnings to be emitted during the build.  Unlike mdbx_txn_straggler it
+ * returns an error code and stores the lag in an output argument.
+ * */
+int mdbxgo_txn_straggler(const MDBX_txn *txn, int *lag, int *percent);
 
 /* ConstCString wraps a null-terminated (const char *) because Go's type system
  * does not represent the 'const' qualifier directly on a function argument and
@@ -29,4 +76,23 @@ int mdbxgo_mdb_put2(MDBX_txn *txn, MDBX_dbi dbi, char *kdata, size_t kn, char *v
  * */
 typedef struct{ const char *p; } mdbxgo_ConstCString;
 
+/* Kinds of the b-tree a page belongs to, as reported to the page visitor.
+ * MDBX identifies the main, GC and meta trees by pseudo-names which are not
+ * valid pointers and must not be seen by Go.
+ * */
+#define MDBXGO_PGWALK_NAMED 0
+#define MDBXGO_PGWALK_MAIN 1
+#define MDBXGO_PGWALK_GC 2
+#define MDBXGO_PGWALK_META 3
+
+/* mdbxgo_env_pgwalk walks the b-tree using a static proxy function that does
+ * dynamic dispatch on ctx.
+ * */
+int mdbxgo_env_pgwalk(MDBX_txn *txn, size_t ctx, int dont_check_keys_ordering);
+
+/* mdbxgo_reader_list enumerates the reader lock table using a static proxy
+ * function that does dynamic dispatch on ctx.
+ * */
+int mdbxgo_reader_list(MDBX_env *env, size_t ctx);
+
 #endif
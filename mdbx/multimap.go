@@ -0,0 +1,189 @@
+package mdbx
+
+// ForEachDup calls fn for each value of key in the DupSort database dbi, in
+// order, until fn returns false or a non-nil error.  ForEachDup returns the
+// error from fn, and nil if key is not found.  The slices passed to fn follow
+// the same RawRead rules as Get.
+func (txn *Txn) ForEachDup(dbi DBI, key []byte, fn func(val []byte) (bool, error)) error {
+	cur, err := txn.OpenCursor(dbi)
+	if err != nil {
+		return err
+	}
+	defer cur.Close()
+
+	_, v, err := cur.Get(key, nil, Set)
+	for err == nil {
+		more, ferr := fn(v)
+		if ferr != nil || !more {
+			return ferr
+		}
+		if err = txn.ctxErr(); err != nil {
+			return err
+		}
+		_, v, err = cur.Get(nil, nil, NextDup)
+	}
+	if IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// CountDups returns the number of values of key in the DupSort database dbi,
+// which is zero if key is not found.
+//
+// See mdbx_cursor_count.
+func (txn *Txn) CountDups(dbi DBI, key []byte) (uint64, error) {
+	cur, err := txn.OpenCursor(dbi)
+	if err != nil {
+		return 0, err
+	}
+	defer cur.Close()
+
+	_, _, err = cur.Get(key, nil, Set)
+	if IsNotFound(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return cur.Count()
+}
+
+// FirstDup returns the first value of key in the DupSort database dbi.
+func (txn *Txn) FirstDup(dbi DBI, key []byte) ([]byte, error) {
+	return txn.getDup(dbi, key, FirstDup)
+}
+
+// LastDup returns the last value of key in the DupSort database dbi.
+func (txn *Txn) LastDup(dbi DBI, key []byte) ([]byte, error) {
+	return txn.getDup(dbi, key, LastDup)
+}
+
+func (txn *Txn) getDup(dbi DBI, key []byte, op uint) ([]byte, error) {
+	cur, err := txn.OpenCursor(dbi)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close()
+
+	if _, _, err = cur.Get(key, nil, Set); err != nil {
+		return nil, err
+	}
+	_, v, err := cur.Get(nil, nil, op)
+	return v, err
+}
+
+// DelDup deletes the value val of key from the DupSort database dbi, leaving
+// the other values of key in place.
+//
+// See mdbx_del.
+func (txn *Txn) DelDup(dbi DBI, key, val []byte) error {
+	return txn.DelwithVal(dbi, key, val)
+}
+
+// DelAllDups deletes key and all of its values from the DupSort database dbi.
+//
+// See mdbx_cursor_del.
+func (txn *Txn) DelAllDups(dbi DBI, key []byte) error {
+	cur, err := txn.OpenCursor(dbi)
+	if err != nil {
+		return err
+	}
+	defer cur.Close()
+
+	if _, _, err = cur.Get(key, nil, Set); err != nil {
+		return err
+	}
+	return cur.Del(AllDups)
+}
+
+// Multimap uses a DupSort database to map each key to a sorted set of
+// values, e.g. the adjacency lists of a graph.  Adding a value which is
+// already present and removing a value which is not are no-ops.
+type Multimap struct {
+	dbi DBI
+}
+
+// NewMultimap returns a Multimap for dbi, which must have been opened with
+// the DupSort flag.
+func NewMultimap(dbi DBI) *Multimap {
+	return &Multimap{dbi: dbi}
+}
+
+// OpenMultimap opens the named database with the DupSort flag added to flags
+// and returns a Multimap for it.
+func (txn *Txn) OpenMultimap(name string, flags uint) (*Multimap, error) {
+	dbi, err := txn.OpenDBI(name, flags|DupSort)
+	if err != nil {
+		return nil, err
+	}
+	return NewMultimap(dbi), nil
+}
+
+// DBI returns the database handle of m.
+func (m *Multimap) DBI() DBI {
+	return m.dbi
+}
+
+// Add adds val to the values of key.
+func (m *Multimap) Add(txn *Txn, key, val []byte) error {
+	err := txn.Put(m.dbi, key, val, NoDupData)
+	if IsErrno(err, KeyExist) {
+		return nil
+	}
+	return err
+}
+
+// Has reports whether val is one of the values of key.
+func (m *Multimap) Has(txn *Txn, key, val []byte) (bool, error) {
+	cur, err := txn.OpenCursor(m.dbi)
+	if err != nil {
+		return false, err
+	}
+	defer cur.Close()
+
+	_, _, err = cur.Get(key, val, GetBoth)
+	if IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Remove removes val from the values of key.
+func (m *Multimap) Remove(txn *Txn, key, val []byte) error {
+	err := txn.DelDup(m.dbi, key, val)
+	if IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// RemoveAll removes key and all of its values.
+func (m *Multimap) RemoveAll(txn *Txn, key []byte) error {
+	err := txn.DelAllDups(m.dbi, key)
+	if IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// Count returns the number of values of key.
+func (m *Multimap) Count(txn *Txn, key []byte) (uint64, error) {
+	return txn.CountDups(m.dbi, key)
+}
+
+// ForEach calls fn for each value of key, see Txn.ForEachDup.
+func (m *Multimap) ForEach(txn *Txn, key []byte, fn func(val []byte) (bool, error)) error {
+	return txn.ForEachDup(m.dbi, key, fn)
+}
+
+// Values returns the values of key, in order.  The slices follow the same
+// RawRead rules as Get.
+func (m *Multimap) Values(txn *Txn, key []byte) ([][]byte, error) {
+	var vals [][]byte
+	err := txn.ForEachDup(m.dbi, key, func(val []byte) (bool, error) {
+		vals = append(vals, val)
+		return true, nil
+	})
+	return vals, err
+}
@@ -0,0 +1,130 @@
+package mdbx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTxn_Dups(t *testing.T) {
+	env, _, teardown := setup(t)
+	defer teardown()
+
+	err := env.Update(func(txn *Txn) error {
+		dbi, err := txn.OpenDBI("dups", Create|DupSort)
+		if err != nil {
+			return err
+		}
+		for _, v := range []string{"3", "1", "2"} {
+			if err = txn.Put(dbi, []byte("k"), []byte(v), 0); err != nil {
+				return err
+			}
+		}
+		if err = txn.Put(dbi, []byte("l"), []byte("x"), 0); err != nil {
+			return err
+		}
+
+		n, err := txn.CountDups(dbi, []byte("k"))
+		if err != nil || n != 3 {
+			t.Errorf("CountDups: %d %v", n, err)
+		}
+		n, err = txn.CountDups(dbi, []byte("missing"))
+		if err != nil || n != 0 {
+			t.Errorf("CountDups of a missing key: %d %v", n, err)
+		}
+		v, err := txn.FirstDup(dbi, []byte("k"))
+		if err != nil || string(v) != "1" {
+			t.Errorf("FirstDup: %q %v", v, err)
+		}
+		v, err = txn.LastDup(dbi, []byte("k"))
+		if err != nil || string(v) != "3" {
+			t.Errorf("LastDup: %q %v", v, err)
+		}
+		if _, err = txn.FirstDup(dbi, []byte("missing")); !IsNotFound(err) {
+			t.Errorf("FirstDup of a missing key: %v", err)
+		}
+
+		var vals []string
+		err = txn.ForEachDup(dbi, []byte("k"), func(val []byte) (bool, error) {
+			vals = append(vals, string(val))
+			return true, nil
+		})
+		if err != nil {
+			return err
+		}
+		if strings.Join(vals, ",") != "1,2,3" {
+			t.Errorf("ForEachDup: %q", vals)
+		}
+
+		if err = txn.DelDup(dbi, []byte("k"), []byte("2")); err != nil {
+			return err
+		}
+		if n, _ = txn.CountDups(dbi, []byte("k")); n != 2 {
+			t.Errorf("values after DelDup: %d (!= 2)", n)
+		}
+		if err = txn.DelAllDups(dbi, []byte("k")); err != nil {
+			return err
+		}
+		if n, _ = txn.CountDups(dbi, []byte("k")); n != 0 {
+			t.Errorf("values after DelAllDups: %d (!= 0)", n)
+		}
+		if n, _ = txn.CountDups(dbi, []byte("l")); n != 1 {
+			t.Errorf("values of another key after DelAllDups: %d (!= 1)", n)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMultimap(t *testing.T) {
+	env, _, teardown := setup(t)
+	defer teardown()
+
+	err := env.Update(func(txn *Txn) error {
+		m, err := txn.OpenMultimap("edges", Create)
+		if err != nil {
+			return err
+		}
+		for _, e := range [][2]string{{"a", "b"}, {"a", "c"}, {"a", "b"}, {"b", "c"}} {
+			if err = m.Add(txn, []byte(e[0]), []byte(e[1])); err != nil {
+				return err
+			}
+		}
+		vals, err := m.Values(txn, []byte("a"))
+		if err != nil {
+			return err
+		}
+		if len(vals) != 2 || string(vals[0]) != "b" || string(vals[1]) != "c" {
+			t.Errorf("values: %q", vals)
+		}
+		ok, err := m.Has(txn, []byte("a"), []byte("c"))
+		if err != nil || !ok {
+			t.Errorf("Has: %v %v", ok, err)
+		}
+		if err = m.Remove(txn, []byte("a"), []byte("c")); err != nil {
+			return err
+		}
+		if err = m.Remove(txn, []byte("a"), []byte("c")); err != nil {
+			return err
+		}
+		ok, err = m.Has(txn, []byte("a"), []byte("c"))
+		if err != nil || ok {
+			t.Errorf("Has after Remove: %v %v", ok, err)
+		}
+		if err = m.RemoveAll(txn, []byte("b")); err != nil {
+			return err
+		}
+		if err = m.RemoveAll(txn, []byte("b")); err != nil {
+			return err
+		}
+		n, err := m.Count(txn, []byte("b"))
+		if err != nil || n != 0 {
+			t.Errorf("Count after RemoveAll: %d %v", n, err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,171 @@
+package mdbx
+
+/*
+#include "mdbx.h"
+#include "mdbxgo.h"
+*/
+import "C"
+
+import (
+	"sync"
+)
+
+// PageType is the type of a page visited by Env.PageWalk.
+//
+// See MDBX_page_type_t.
+type PageType int
+
+// Page types reported by Env.PageWalk.
+const (
+	PageBroken          PageType = C.MDBX_page_broken
+	PageMeta            PageType = C.MDBX_page_meta
+	PageLarge           PageType = C.MDBX_page_large // Overflow pages holding a single large value
+	PageBranch          PageType = C.MDBX_page_branch
+	PageLeaf            PageType = C.MDBX_page_leaf
+	PageDupFixedLeaf    PageType = C.MDBX_page_dupfixed_leaf
+	SubpageLeaf         PageType = C.MDBX_subpage_leaf
+	SubpageDupFixedLeaf PageType = C.MDBX_subpage_dupfixed_leaf
+	SubpageBroken       PageType = C.MDBX_subpage_broken
+)
+
+var pageTypeNames = [...]string{
+	PageBroken:          "broken",
+	PageMeta:            "meta",
+	PageLarge:           "large",
+	PageBranch:          "branch",
+	PageLeaf:            "leaf",
+	PageDupFixedLeaf:    "dupfixed_leaf",
+	SubpageLeaf:         "subpage_leaf",
+	SubpageDupFixedLeaf: "subpage_dupfixed_leaf",
+	SubpageBroken:       "subpage_broken",
+}
+
+func (t PageType) String() string {
+	if t < 0 || int(t) >= len(pageTypeNames) {
+		return "unknown"
+	}
+	return pageTypeNames[t]
+}
+
+// Pseudo-names reported in PageInfo.DBI for pages which do not belong to a
+// named database.  The names match the ones used by the mdbx_chk tool.
+const (
+	PageWalkMain = "@MAIN" // The main (root) database
+	PageWalkGC   = "@GC"   // The garbage collector database
+	PageWalkMeta = "@META" // The meta pages
+)
+
+// PageInfo describes a page visited by Env.PageWalk.  Sub-pages are embedded
+// into a leaf page and are reported before the page holding them, their space
+// is accounted for in both.
+//
+// See MDBX_pgvisitor_func.
+type PageInfo struct {
+	Pgno         uint64   // Number of the first page
+	Number       uint     // Count of pages, greater than one for large pages and zero for sub-pages
+	Deep         int      // Depth of the page within its b-tree
+	DBI          string   // Name of the database owning the page, or one of the PageWalk pseudo-names
+	PageSize     uint     // Size of the page(s) in bytes
+	Type         PageType // Type of the page
+	Err          error    // Non-nil if the page is broken
+	Entries      uint     // Count of entries in the page
+	PayloadBytes uint     // Bytes occupied by the entries
+	HeaderBytes  uint     // Bytes occupied by page and node headers
+	UnusedBytes  uint     // Bytes left unused
+}
+
+// PageVisitor is called by Env.PageWalk for every page.  Returning a non-nil
+// error stops the walk and the error is returned from Env.PageWalk.
+type PageVisitor func(page *PageInfo) error
+
+// pgwalk holds the state of a single Env.PageWalk call, referenced from C by
+// an integer key.
+type pgwalk struct {
+	fn  PageVisitor
+	err error
+}
+
+var pgwalks = struct {
+	sync.Mutex
+	m    map[C.size_t]*pgwalk
+	next C.size_t
+}{m: map[C.size_t]*pgwalk{}}
+
+func registerPgwalk(w *pgwalk) C.size_t {
+	pgwalks.Lock()
+	defer pgwalks.Unlock()
+	pgwalks.next++
+	pgwalks.m[pgwalks.next] = w
+	return pgwalks.next
+}
+
+func deregisterPgwalk(ctx C.size_t) {
+	pgwalks.Lock()
+	delete(pgwalks.m, ctx)
+	pgwalks.Unlock()
+}
+
+func lookupPgwalk(ctx C.size_t) *pgwalk {
+	pgwalks.Lock()
+	defer pgwalks.Unlock()
+	return pgwalks.m[ctx]
+}
+
+//export mdbxgoPgVisitorBridge
+func mdbxgoPgVisitorBridge(ctx C.size_t, pgno C.uint64_t, number C.uint, deep C.int, kind C.int, dbi C.mdbxgo_ConstCString,
+	pageSize C.size_t, typ C.int, perr C.int, nentries, payload, header, unused C.size_t) C.int {
+	w := lookupPgwalk(ctx)
+	if w == nil {
+		return C.MDBX_EINVAL
+	}
+	page := &PageInfo{
+		Pgno:         uint64(pgno),
+		Number:       uint(number),
+		Deep:         int(deep),
+		PageSize:     uint(pageSize),
+		Type:         PageType(typ),
+		Err:          operrno("mdbx_env_pgwalk", perr),
+		Entries:      uint(nentries),
+		PayloadBytes: uint(payload),
+		HeaderBytes:  uint(header),
+		UnusedBytes:  uint(unused),
+	}
+	switch kind {
+	case C.MDBXGO_PGWALK_MAIN:
+		page.DBI = PageWalkMain
+	case C.MDBXGO_PGWALK_GC:
+		page.DBI = PageWalkGC
+	case C.MDBXGO_PGWALK_META:
+		page.DBI = PageWalkMeta
+	default:
+		page.DBI = C.GoString(dbi.p)
+	}
+	w.err = w.fn(page)
+	if w.err != nil {
+		return C.MDBX_EINTR
+	}
+	return success
+}
+
+// PageWalk calls fn for every page of the environment, as seen by a new
+// readonly transaction.  It is meant for space usage reports, such as finding
+// which database holds the most large (overflow) pages.  Walking the pages of
+// a big environment is slow and holds the transaction open meanwhile.
+//
+// See mdbx_env_pgwalk.
+func (env *Env) PageWalk(fn PageVisitor) error {
+	return env.View(func(txn *Txn) error {
+		return txn.pageWalk(fn)
+	})
+}
+
+func (txn *Txn) pageWalk(fn PageVisitor) error {
+	w := &pgwalk{fn: fn}
+	ctx := registerPgwalk(w)
+	defer deregisterPgwalk(ctx)
+	ret := C.mdbxgo_env_pgwalk(txn._txn, ctx, 0)
+	if w.err != nil {
+		return w.err
+	}
+	return operrno("mdbx_env_pgwalk", ret)
+}
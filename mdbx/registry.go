@@ -0,0 +1,119 @@
+package mdbx
+
+/*
+#include "mdbx.h"
+*/
+import "C"
+
+import (
+	"sync"
+)
+
+// dbiPersistentFlags are the flags stored with a database, which must match
+// between handles to the same database.
+const dbiPersistentFlags = C.MDBX_REVERSEKEY | C.MDBX_DUPSORT | C.MDBX_INTEGERKEY |
+	C.MDBX_DUPFIXED | C.MDBX_INTEGERDUP | C.MDBX_REVERSEDUP
+
+// dbiRegistry caches the handles opened by Env.DBI.  Databases are opened
+// with opening held, but not mu, so that a write transaction calling forget
+// cannot deadlock with Env.DBI waiting for it.
+type dbiRegistry struct {
+	opening sync.Mutex
+	mu      sync.Mutex
+	dbis    map[string]dbiEntry
+	gen     uint64 // incremented by forget
+}
+
+type dbiEntry struct {
+	dbi   DBI
+	flags uint // persistent flags of the database
+}
+
+// DBI returns a handle to the named database, opening it in a transaction of
+// its own on first use and returning the cached handle afterwards.  An empty
+// name denotes the root database.  If flags include Create a write
+// transaction is used, which creates the database if it does not exist.
+//
+// DBI returns Incompatible if flags ask for another kind of database than the
+// cached one, e.g. DupSort for a database without sorted duplicates.  The
+// cached handle is forgotten when it is closed by CloseDBI or the database is
+// deleted by Txn.Drop.
+//
+// DBI may be called from any goroutine.  It must not be called from within a
+// transaction, which would deadlock.
+func (env *Env) DBI(name string, flags uint) (DBI, error) {
+	r := &env.dbis
+	if e, ok := r.lookup(name); ok {
+		return e.check(flags)
+	}
+
+	r.opening.Lock()
+	defer r.opening.Unlock()
+	if e, ok := r.lookup(name); ok {
+		return e.check(flags)
+	}
+	r.mu.Lock()
+	gen := r.gen
+	r.mu.Unlock()
+
+	var e dbiEntry
+	open := func(txn *Txn) (err error) {
+		if name == "" {
+			e.dbi, err = txn.OpenRoot(flags)
+		} else {
+			e.dbi, err = txn.OpenDBI(name, flags)
+		}
+		if err != nil {
+			return err
+		}
+		e.flags, _, err = txn.DBIFlags(e.dbi)
+		return err
+	}
+	var err error
+	if flags&Create != 0 {
+		err = env.Update(open)
+	} else {
+		err = env.View(open)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.gen == gen {
+		// the handle was not closed or dropped meanwhile
+		if r.dbis == nil {
+			r.dbis = map[string]dbiEntry{}
+		}
+		r.dbis[name] = e
+	}
+	return e.dbi, nil
+}
+
+func (r *dbiRegistry) lookup(name string) (dbiEntry, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.dbis[name]
+	return e, ok
+}
+
+// check returns the handle of e if flags are compatible with the database.
+func (e dbiEntry) check(flags uint) (DBI, error) {
+	if flags&dbiPersistentFlags != e.flags {
+		return 0, operrno("mdbx_dbi_open", C.MDBX_INCOMPATIBLE)
+	}
+	return e.dbi, nil
+}
+
+// forget removes dbi from the cache of handles opened by Env.DBI.
+func (r *dbiRegistry) forget(dbi DBI) {
+	r.mu.Lock()
+	r.gen++
+	for name, e := range r.dbis {
+		if e.dbi == dbi {
+			delete(r.dbis, name)
+		}
+	}
+	r.mu.Unlock()
+}
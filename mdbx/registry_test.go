@@ -0,0 +1,74 @@
+package mdbx
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestEnv_DBI(t *testing.T) {
+	env, _, teardown := setup(t)
+	defer teardown()
+
+	if _, err := env.DBI("t", 0); !IsNotFound(err) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	dbis := make([]DBI, 8)
+	var wg sync.WaitGroup
+	for i := range dbis {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var err error
+			dbis[i], err = env.DBI("t", Create|DupSort)
+			if err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	for _, dbi := range dbis {
+		if dbi != dbis[0] {
+			t.Fatalf("handles differ: %v", dbis)
+		}
+	}
+	dbi, err := env.DBI("t", DupSort)
+	if err != nil || dbi != dbis[0] {
+		t.Errorf("cached handle: %v %v", dbi, err)
+	}
+	if _, err = env.DBI("t", 0); !IsErrno(err, Incompatible) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	root, err := env.DBI("", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root == dbi {
+		t.Errorf("root handle %v is the handle of a named database", root)
+	}
+
+	err = env.Update(func(txn *Txn) error {
+		return txn.Drop(dbi, true)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = env.DBI("t", DupSort); !IsNotFound(err) {
+		t.Errorf("handle of a dropped database: %v", err)
+	}
+
+	dbi, err = env.DBI("u", Create)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.CloseDBI(dbi)
+	env.dbis.mu.Lock()
+	_, ok := env.dbis.dbis["u"]
+	env.dbis.mu.Unlock()
+	if ok {
+		t.Errorf("closed handle is still cached")
+	}
+	if _, err = env.DBI("u", 0); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,269 @@
+package mdbx
+
+/*
+#include "mdbx.h"
+*/
+import "C"
+
+import (
+	"bytes"
+)
+
+// ScanOptions selects the items visited by Txn.Scan and Iterator.
+//
+// Start and End bound the keys from below and above regardless of the
+// direction of iteration.  Start is inclusive and End is exclusive unless
+// StartExclusive or EndInclusive say otherwise.  A nil bound leaves that side
+// of the range open.  Bounds and prefixes are compared with bytes.Compare, so
+// the database must use the default key order (not ReverseKey or IntegerKey).
+type ScanOptions struct {
+	Start          []byte // Lower bound of the keys
+	End            []byte // Upper bound of the keys
+	StartExclusive bool   // Skip a key equal to Start
+	EndInclusive   bool   // Include a key equal to End
+	Prefix         []byte // Only visit keys with this prefix
+	Reverse        bool   // Visit keys in descending order, from the upper bound
+	Limit          int    // Stop after this many items, zero means no limit
+	KeysOnly       bool   // Do not retrieve values, which are reported as nil
+	Raw            bool   // Reference the memory map as if Txn.RawRead was set
+}
+
+// prefixEnd returns the smallest key greater than all keys having prefix, or
+// nil if there is no such key.
+func prefixEnd(prefix []byte) []byte {
+	for i := len(prefix) - 1; i >= 0; i-- {
+		if prefix[i] != 0xff {
+			end := make([]byte, i+1)
+			copy(end, prefix)
+			end[i]++
+			return end
+		}
+	}
+	return nil
+}
+
+// aboveLower reports whether k is within the lower bounds of opts.
+func (opts *ScanOptions) aboveLower(k []byte) bool {
+	if opts.Start != nil {
+		c := bytes.Compare(k, opts.Start)
+		if c < 0 || c == 0 && opts.StartExclusive {
+			return false
+		}
+	}
+	return opts.Prefix == nil || bytes.Compare(k, opts.Prefix) >= 0
+}
+
+// belowUpper reports whether k is within the upper bounds of opts.
+func (opts *ScanOptions) belowUpper(k []byte) bool {
+	if opts.End != nil {
+		c := bytes.Compare(k, opts.End)
+		if c > 0 || c == 0 && !opts.EndInclusive {
+			return false
+		}
+	}
+	return opts.Prefix == nil || bytes.HasPrefix(k, opts.Prefix) || bytes.Compare(k, opts.Prefix) < 0
+}
+
+// Iterator is a pull-style iterator over a range of items in a database.  It
+// must be closed after use, typical use looks like:
+//
+//	it, err := txn.NewIterator(dbi, mdbx.ScanOptions{Prefix: []byte("user/")})
+//	if err != nil {
+//		return err
+//	}
+//	defer it.Close()
+//	for it.Next() {
+//		fmt.Printf("%s: %s\n", it.Key(), it.Value())
+//	}
+//	return it.Err()
+//
+// If the transaction has RawRead set, or ScanOptions.Raw is true, the slices
+// returned by Key and Value reference readonly memory which is only valid
+// until the transaction terminates.  Otherwise they are copies owned by the
+// caller.
+type Iterator struct {
+	txn     *Txn
+	cur     *Cursor
+	opts    ScanOptions
+	key     []byte
+	val     []byte
+	err     error
+	n       int
+	started bool
+	done    bool
+}
+
+// NewIterator returns an Iterator over the items of database dbi selected by
+// opts.  The Iterator is positioned before the first item.
+func (txn *Txn) NewIterator(dbi DBI, opts ScanOptions) (*Iterator, error) {
+	cur, err := txn.OpenCursor(dbi)
+	if err != nil {
+		return nil, err
+	}
+	return &Iterator{txn: txn, cur: cur, opts: opts}, nil
+}
+
+// Next advances the iterator to the next item and reports whether there is
+// one.  When Next returns false the iteration is over and Err reports any
+// error that stopped it, including the end of the context of the transaction
+// (see Env.ViewContext).
+func (it *Iterator) Next() bool {
+	if it.done {
+		return false
+	}
+	it.key, it.val = nil, nil
+	if it.opts.Limit > 0 && it.n >= it.opts.Limit {
+		it.done = true
+		return false
+	}
+	if err := it.txn.ctxErr(); err != nil {
+		it.err = err
+		it.done = true
+		return false
+	}
+	var ok bool
+	var err error
+	if !it.started {
+		it.started = true
+		ok, err = it.seek()
+	} else if it.opts.Reverse {
+		ok, err = it.step(Prev)
+	} else {
+		ok, err = it.step(Next)
+	}
+	if err == nil && ok {
+		k := getBytes(it.txn.key)
+		if it.opts.Reverse {
+			ok = it.opts.aboveLower(k)
+		} else {
+			ok = it.opts.belowUpper(k)
+		}
+	}
+	if err != nil || !ok {
+		it.clear()
+		it.err = err
+		it.done = true
+		return false
+	}
+	it.key = it.bytes(it.txn.key)
+	if !it.opts.KeysOnly {
+		it.val = it.bytes(it.txn.val)
+	}
+	it.clear()
+	it.n++
+	return true
+}
+
+// seek positions the cursor at the first item within the bounds on the side
+// iteration starts from.
+func (it *Iterator) seek() (bool, error) {
+	opts := &it.opts
+	if !opts.Reverse {
+		from := opts.Start
+		if opts.Prefix != nil && bytes.Compare(opts.Prefix, from) > 0 {
+			from = opts.Prefix
+		}
+		ok, err := it.seekRange(from, First)
+		for ok && err == nil && !opts.aboveLower(getBytes(it.txn.key)) {
+			ok, err = it.step(Next)
+		}
+		return ok, err
+	}
+
+	to := opts.End
+	if end := prefixEnd(opts.Prefix); end != nil && (to == nil || bytes.Compare(end, to) < 0) {
+		to = end
+	}
+	ok, err := it.seekRange(to, Last)
+	if err == nil && !ok && to != nil {
+		// all keys are less than the upper bound
+		ok, err = it.step(Last)
+	}
+	for ok && err == nil && !opts.belowUpper(getBytes(it.txn.key)) {
+		ok, err = it.step(Prev)
+	}
+	return ok, err
+}
+
+// seekRange positions the cursor at the first key not less than key, or with
+// op if key is empty.
+func (it *Iterator) seekRange(key []byte, op uint) (bool, error) {
+	if len(key) == 0 {
+		return it.step(op)
+	}
+	return it.found(it.cur.getVal1(key, SetRange))
+}
+
+func (it *Iterator) step(op uint) (bool, error) {
+	return it.found(it.cur.getVal0(op))
+}
+
+// found converts NotFound, which ends the iteration, into false.  On failure
+// the key may still reference the Go memory passed to getVal1, which must not
+// be passed back to C, so it is cleared.
+func (it *Iterator) found(err error) (bool, error) {
+	if err != nil {
+		it.clear()
+	}
+	if IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// bytes returns the data of val, copied unless RawRead or opts.Raw is set.
+func (it *Iterator) bytes(val *C.MDBX_val) []byte {
+	if it.opts.Raw {
+		return getBytes(val)
+	}
+	return it.txn.bytes(val)
+}
+
+func (it *Iterator) clear() {
+	*it.txn.key = C.MDBX_val{}
+	*it.txn.val = C.MDBX_val{}
+}
+
+// Key returns the key of the current item.
+func (it *Iterator) Key() []byte {
+	return it.key
+}
+
+// Value returns the value of the current item, nil if ScanOptions.KeysOnly
+// is set.
+func (it *Iterator) Value() []byte {
+	return it.val
+}
+
+// Err returns the error which stopped the iteration, if any.
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// Close closes the cursor used by the iterator.  Close may be called more
+// than once.
+func (it *Iterator) Close() {
+	if it.cur != nil {
+		it.cur.Close()
+		it.cur = nil
+	}
+	it.done = true
+}
+
+// Scan calls fn for the items of database dbi selected by opts, in order.
+// Scan stops when fn returns false or a non-nil error, and returns the error.
+// The slices passed to fn follow the same RawRead rules as Iterator.
+func (txn *Txn) Scan(dbi DBI, opts ScanOptions, fn func(k, v []byte) (bool, error)) error {
+	it, err := txn.NewIterator(dbi, opts)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+	for it.Next() {
+		more, err := fn(it.Key(), it.Value())
+		if err != nil || !more {
+			return err
+		}
+	}
+	return it.Err()
+}
@@ -0,0 +1,161 @@
+package mdbx
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTxn_Scan(t *testing.T) {
+	env, _, teardown := setup(t)
+	defer teardown()
+
+	keys := []string{"a", "b", "b/1", "b/2", "b/3", "b\xff", "c", "d"}
+	var dbi DBI
+	err := env.Update(func(txn *Txn) (err error) {
+		dbi, err = txn.OpenRoot(0)
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if err = txn.Put(dbi, []byte(k), []byte("v"+k), 0); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		opts ScanOptions
+		keys string
+	}{
+		{ScanOptions{}, "a b b/1 b/2 b/3 b\xff c d"},
+		{ScanOptions{Reverse: true}, "d c b\xff b/3 b/2 b/1 b a"},
+		{ScanOptions{Start: []byte("b/1"), End: []byte("c")}, "b/1 b/2 b/3 b\xff"},
+		{ScanOptions{Start: []byte("b/1"), End: []byte("c"), StartExclusive: true, EndInclusive: true}, "b/2 b/3 b\xff c"},
+		{ScanOptions{Start: []byte("b/1"), End: []byte("c"), Reverse: true}, "b\xff b/3 b/2 b/1"},
+		{ScanOptions{Start: []byte("b/1"), End: []byte("c"), StartExclusive: true, EndInclusive: true, Reverse: true}, "c b\xff b/3 b/2"},
+		{ScanOptions{Start: []byte("bb"), End: []byte("zz"), Reverse: true}, "d c b\xff"},
+		{ScanOptions{Prefix: []byte("b/")}, "b/1 b/2 b/3"},
+		{ScanOptions{Prefix: []byte("b/"), Reverse: true}, "b/3 b/2 b/1"},
+		{ScanOptions{Prefix: []byte("b/"), Start: []byte("b/2")}, "b/2 b/3"},
+		{ScanOptions{Prefix: []byte("b/"), End: []byte("b/3"), Reverse: true}, "b/2 b/1"},
+		{ScanOptions{Prefix: []byte("b\xff")}, "b\xff"},
+		{ScanOptions{Prefix: []byte("b\xff"), Reverse: true}, "b\xff"},
+		{ScanOptions{Prefix: []byte("x")}, ""},
+		{ScanOptions{Prefix: []byte("x"), Reverse: true}, ""},
+		{ScanOptions{Limit: 2}, "a b"},
+		{ScanOptions{Prefix: []byte("b"), Limit: 3, Reverse: true}, "b\xff b/3 b/2"},
+	} {
+		var got []string
+		err := env.View(func(txn *Txn) error {
+			return txn.Scan(dbi, test.opts, func(k, v []byte) (bool, error) {
+				if string(v) != "v"+string(k) {
+					t.Errorf("%+v: key %q: %q", test.opts, k, v)
+				}
+				got = append(got, string(k))
+				return true, nil
+			})
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(got, " ") != test.keys {
+			t.Errorf("%+v: %q (!= %q)", test.opts, strings.Join(got, " "), test.keys)
+		}
+	}
+
+	errStop := errors.New("stop")
+	n := 0
+	err = env.View(func(txn *Txn) error {
+		txn.RawRead = true
+		return txn.Scan(dbi, ScanOptions{KeysOnly: true}, func(k, v []byte) (bool, error) {
+			if v != nil {
+				t.Errorf("value with KeysOnly: %q", v)
+			}
+			n++
+			if n == 3 {
+				return false, errStop
+			}
+			return true, nil
+		})
+	})
+	if err != errStop || n != 3 {
+		t.Errorf("unexpected result: %v after %d items", err, n)
+	}
+
+	err = env.View(func(txn *Txn) error {
+		raw, err := txn.GetRaw(dbi, []byte("a"))
+		if err != nil {
+			return err
+		}
+		if v, _ := txn.Get(dbi, []byte("a")); &v[0] == &raw[0] {
+			t.Error("Get without RawRead shares memory with GetRaw")
+		}
+		for _, rawopt := range []bool{false, true} {
+			err = txn.Scan(dbi, ScanOptions{Limit: 1, Raw: rawopt}, func(k, v []byte) (bool, error) {
+				if shared := &v[0] == &raw[0]; shared != rawopt {
+					t.Errorf("Raw %v: value shared %v", rawopt, shared)
+				}
+				return true, nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if txn.RawRead {
+			t.Error("RawRead set")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIterator(t *testing.T) {
+	env, _, teardown := setup(t)
+	defer teardown()
+
+	err := env.Update(func(txn *Txn) error {
+		dbi, err := txn.OpenRoot(0)
+		if err != nil {
+			return err
+		}
+		for _, k := range []string{"k1", "k2", "k3"} {
+			if err = txn.Put(dbi, []byte(k), []byte(k), 0); err != nil {
+				return err
+			}
+		}
+
+		it, err := txn.NewIterator(dbi, ScanOptions{Start: []byte("k2")})
+		if err != nil {
+			return err
+		}
+		defer it.Close()
+		var got []string
+		for it.Next() {
+			got = append(got, string(it.Key())+"="+string(it.Value()))
+		}
+		if err = it.Err(); err != nil {
+			return err
+		}
+		if strings.Join(got, " ") != "k2=k2 k3=k3" {
+			t.Errorf("unexpected items: %q", got)
+		}
+		if it.Next() {
+			t.Errorf("Next after the end of the iteration")
+		}
+		it.Close()
+		if it.Next() {
+			t.Errorf("Next after Close")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
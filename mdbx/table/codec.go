@@ -0,0 +1,182 @@
+package table
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// Codec converts the keys or values of a Table to and from the bytes stored
+// in the database.
+type Codec interface {
+	// Encode returns the encoding of v.  Encode returns an error if v does
+	// not have the type the codec handles.
+	Encode(v interface{}) ([]byte, error)
+
+	// Decode returns the value encoded by data.  Unless the codec is a
+	// ZeroCopyCodec, data may point into the memory map and the returned
+	// value must not reference it.
+	Decode(data []byte) (interface{}, error)
+}
+
+// ZeroCopyCodec is implemented by codecs whose decoded values reference the
+// data passed to Decode, such as Raw.  A Table passes them memory-mapped data
+// only if the transaction has RawRead set, and a copy otherwise.  Other codecs
+// always decode directly from the memory map.
+type ZeroCopyCodec interface {
+	Codec
+
+	// ZeroCopy returns true if decoded values reference the decoded data.
+	ZeroCopy() bool
+}
+
+// zeroCopy reports whether c decodes values referencing the decoded data.
+func zeroCopy(c Codec) bool {
+	z, ok := c.(ZeroCopyCodec)
+	return ok && z.ZeroCopy()
+}
+
+func typeError(codec string, v interface{}) error {
+	return fmt.Errorf("table: %s codec cannot encode %T", codec, v)
+}
+
+// Raw is a codec for []byte values, which are stored as is.  Values decoded
+// in a transaction with RawRead set reference the memory map and are only
+// valid until the transaction terminates.
+var Raw ZeroCopyCodec = rawCodec{}
+
+type rawCodec struct{}
+
+func (rawCodec) Encode(v interface{}) ([]byte, error) {
+	b, ok := v.([]byte)
+	if !ok {
+		return nil, typeError("raw", v)
+	}
+	return b, nil
+}
+
+func (rawCodec) Decode(data []byte) (interface{}, error) {
+	return data, nil
+}
+
+func (rawCodec) ZeroCopy() bool {
+	return true
+}
+
+// String is a codec for string values, which are stored as their bytes.
+// Decoded strings are always copies, even with RawRead set.
+var String Codec = stringCodec{}
+
+type stringCodec struct{}
+
+func (stringCodec) Encode(v interface{}) ([]byte, error) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, typeError("string", v)
+	}
+	return []byte(s), nil
+}
+
+func (stringCodec) Decode(data []byte) (interface{}, error) {
+	return string(data), nil
+}
+
+// Uint64 is a codec for uint64 values, which are stored in 8 bytes in
+// big-endian order so that the keys of a Table sort numerically.
+var Uint64 Codec = uint64Codec{}
+
+type uint64Codec struct{}
+
+func (uint64Codec) Encode(v interface{}) ([]byte, error) {
+	n, ok := v.(uint64)
+	if !ok {
+		return nil, typeError("uint64", v)
+	}
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, n)
+	return b, nil
+}
+
+func (uint64Codec) Decode(data []byte) (interface{}, error) {
+	if len(data) != 8 {
+		return nil, fmt.Errorf("table: uint64 codec cannot decode %d bytes", len(data))
+	}
+	return binary.BigEndian.Uint64(data), nil
+}
+
+// JSON returns a codec which stores values encoded by encoding/json and
+// decodes them to the type of proto.  For example JSON(User{}) decodes to
+// User values and JSON(&User{}) to *User values.  A nil proto decodes to the
+// generic types of json.Unmarshal.
+func JSON(proto interface{}) Codec {
+	return jsonCodec{typ: reflect.TypeOf(proto)}
+}
+
+type jsonCodec struct {
+	typ reflect.Type
+}
+
+func (c jsonCodec) Encode(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (c jsonCodec) Decode(data []byte) (interface{}, error) {
+	p := newValue(c.typ)
+	if err := json.Unmarshal(data, p.Interface()); err != nil {
+		return nil, err
+	}
+	return decoded(c.typ, p), nil
+}
+
+// Gob returns a codec which stores values encoded by encoding/gob and
+// decodes them to the type of proto, like JSON.  Each value is encoded with
+// its own type information.  Gob panics if proto is nil.
+func Gob(proto interface{}) Codec {
+	if proto == nil {
+		panic("table: Gob needs a non-nil proto")
+	}
+	return gobCodec{typ: reflect.TypeOf(proto)}
+}
+
+type gobCodec struct {
+	typ reflect.Type
+}
+
+func (c gobCodec) Encode(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c gobCodec) Decode(data []byte) (interface{}, error) {
+	p := newValue(c.typ)
+	if err := gob.NewDecoder(bytes.NewReader(data)).DecodeValue(p); err != nil {
+		return nil, err
+	}
+	return decoded(c.typ, p), nil
+}
+
+// newValue returns a pointer to a new value to decode a value of type typ
+// into.  A nil typ decodes into an interface{}.
+func newValue(typ reflect.Type) reflect.Value {
+	switch {
+	case typ == nil:
+		return reflect.New(reflect.TypeOf((*interface{})(nil)).Elem())
+	case typ.Kind() == reflect.Ptr:
+		return reflect.New(typ.Elem())
+	}
+	return reflect.New(typ)
+}
+
+// decoded returns the value of type typ decoded into p by newValue.
+func decoded(typ reflect.Type, p reflect.Value) interface{} {
+	if typ != nil && typ.Kind() == reflect.Ptr {
+		return p.Interface()
+	}
+	return p.Elem().Interface()
+}
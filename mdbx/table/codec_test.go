@@ -0,0 +1,78 @@
+package table
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+type user struct {
+	Name string
+	Age  int
+	Tags []string
+}
+
+func TestCodecs(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		codec Codec
+		val   interface{}
+		bad   interface{}
+	}{
+		{"raw", Raw, []byte("hello"), "hello"},
+		{"string", String, "hello", []byte("hello")},
+		{"uint64", Uint64, uint64(1) << 40, 1},
+		{"json", JSON(user{}), user{Name: "gopher", Age: 11, Tags: []string{"a"}}, make(chan int)},
+		{"json pointer", JSON(&user{}), &user{Name: "gopher"}, nil},
+		{"json generic", JSON(nil), map[string]interface{}{"a": 1.5}, nil},
+		{"gob", Gob(user{}), user{Name: "gopher", Age: 11}, nil},
+		{"gob pointer", Gob(&user{}), &user{Name: "gopher", Tags: []string{"a", "b"}}, nil},
+	} {
+		data, err := test.codec.Encode(test.val)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		v, err := test.codec.Decode(data)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(v, test.val) {
+			t.Errorf("%s: decoded %#v, want %#v", test.name, v, test.val)
+		}
+		if test.bad != nil {
+			if _, err := test.codec.Encode(test.bad); err == nil {
+				t.Errorf("%s: encoded %T", test.name, test.bad)
+			}
+		}
+	}
+}
+
+func TestUint64_Order(t *testing.T) {
+	var prev []byte
+	for _, n := range []uint64{0, 1, 255, 256, 1 << 32, 1<<64 - 1} {
+		b, err := Uint64.Encode(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if prev != nil && bytes.Compare(prev, b) >= 0 {
+			t.Errorf("encoding of %d does not sort after %x", n, prev)
+		}
+		prev = b
+	}
+	if _, err := Uint64.Decode([]byte{1, 2}); err == nil {
+		t.Error("decoded 2 bytes")
+	}
+}
+
+func TestZeroCopy(t *testing.T) {
+	data := []byte("hello")
+	v, _ := Raw.Decode(data)
+	if &v.([]byte)[0] != &data[0] {
+		t.Error("Raw copied the data")
+	}
+	if !zeroCopy(Raw) || zeroCopy(String) || zeroCopy(JSON(nil)) {
+		t.Error("unexpected zero copy codecs")
+	}
+}
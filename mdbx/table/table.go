@@ -0,0 +1,126 @@
+/*
+Package table binds a database of an mdbx.Env to codecs for its keys and
+values, so that applications read and write decoded values instead of bytes.
+
+	users, err := table.Open(env, "users", mdbx.Create, table.Uint64, table.JSON(User{}))
+	if err != nil {
+		return err
+	}
+	err = env.Update(func(txn *mdbx.Txn) error {
+		return users.Put(txn, uint64(42), User{Name: "gopher"}, 0)
+	})
+
+Values are decoded straight from the memory map, without changing the RawRead
+setting of the transaction.  Only codecs implementing ZeroCopyCodec, whose
+values reference the decoded bytes, are given a copy unless the transaction
+has RawRead set.  Raw is the only such codec, the others, String included,
+always return values independent of the memory map.
+*/
+package table
+
+import (
+	"github.com/xzfkiller/mdbx-go/mdbx"
+)
+
+// Table is a database with codecs for its keys and values.  A Table is only
+// a DBI and two codecs, each method works in the transaction it is given, so
+// a single Table can serve every goroutine of a program.
+type Table struct {
+	dbi mdbx.DBI
+	key Codec
+	val Codec
+}
+
+// New returns a Table for the database dbi.
+func New(dbi mdbx.DBI, key, val Codec) *Table {
+	return &Table{dbi: dbi, key: key, val: val}
+}
+
+// Open returns a Table for the named database of env.  The database is
+// opened by env.DBI in a transaction of its own, so tables are opened before
+// the transactions which use them, typically at startup.
+func Open(env *mdbx.Env, name string, flags uint, key, val Codec) (*Table, error) {
+	dbi, err := env.DBI(name, flags)
+	if err != nil {
+		return nil, err
+	}
+	return New(dbi, key, val), nil
+}
+
+// DBI returns the database handle of t.
+func (t *Table) DBI() mdbx.DBI {
+	return t.dbi
+}
+
+// EncodeKey returns the encoding of key, e.g. to build the bounds of a Scan.
+func (t *Table) EncodeKey(key interface{}) ([]byte, error) {
+	return t.key.Encode(key)
+}
+
+// decode decodes memory-mapped data with c, copying it first for a
+// zero-copy codec unless the caller asked for raw data.
+func decode(c Codec, data []byte, raw bool) (interface{}, error) {
+	if !raw && zeroCopy(c) {
+		data = append([]byte(nil), data...)
+	}
+	return c.Decode(data)
+}
+
+// Get returns the decoded value of key.  If key is not found Get returns an
+// error for which mdbx.IsNotFound is true.
+func (t *Table) Get(txn *mdbx.Txn, key interface{}) (interface{}, error) {
+	k, err := t.key.Encode(key)
+	if err != nil {
+		return nil, err
+	}
+	v, err := txn.GetRaw(t.dbi, k)
+	if err != nil {
+		return nil, err
+	}
+	return decode(t.val, v, txn.RawRead)
+}
+
+// Put stores val under key, passing flags to Txn.Put.
+func (t *Table) Put(txn *mdbx.Txn, key, val interface{}, flags uint) error {
+	k, err := t.key.Encode(key)
+	if err != nil {
+		return err
+	}
+	v, err := t.val.Encode(val)
+	if err != nil {
+		return err
+	}
+	return txn.Put(t.dbi, k, v, flags)
+}
+
+// Delete deletes key.  If key is not found Delete returns an error for which
+// mdbx.IsNotFound is true.
+func (t *Table) Delete(txn *mdbx.Txn, key interface{}) error {
+	k, err := t.key.Encode(key)
+	if err != nil {
+		return err
+	}
+	return txn.Del(t.dbi, k, nil)
+}
+
+// Scan calls fn with the decoded keys and values of the items selected by
+// opts, see mdbx.Txn.Scan.  The bounds and prefix in opts are encoded keys,
+// see EncodeKey.  With opts.KeysOnly fn is passed nil values, and opts.Raw
+// has the effect of RawRead.
+func (t *Table) Scan(txn *mdbx.Txn, opts mdbx.ScanOptions, fn func(key, val interface{}) (bool, error)) error {
+	raw := txn.RawRead || opts.Raw
+	opts.Raw = true
+	return txn.Scan(t.dbi, opts, func(k, v []byte) (bool, error) {
+		key, err := decode(t.key, k, raw)
+		if err != nil {
+			return false, err
+		}
+		var val interface{}
+		if !opts.KeysOnly {
+			if val, err = decode(t.val, v, raw); err != nil {
+				return false, err
+			}
+		}
+		return fn(key, val)
+	})
+}
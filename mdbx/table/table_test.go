@@ -0,0 +1,149 @@
+package table
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/xzfkiller/mdbx-go/mdbx"
+)
+
+// openEnv opens an environment in a temporary directory, both of which are
+// removed when the test ends.
+func openEnv(t *testing.T) *mdbx.Env {
+	path, err := ioutil.TempDir("", "table_test")
+	if err != nil {
+		t.Fatalf("Cannot create temporary directory: %s", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(path) })
+	env, err := mdbx.NewEnv()
+	if err != nil {
+		t.Fatalf("Cannot create environment: %s", err)
+	}
+	t.Cleanup(func() { env.Close() })
+	if err = env.SetMaxDBs(4); err != nil {
+		t.Fatalf("Cannot set max dbs: %s", err)
+	}
+	if err = env.Open(path); err != nil {
+		t.Fatalf("Cannot open environment: %s", err)
+	}
+	return env
+}
+
+func TestTable(t *testing.T) {
+	env := openEnv(t)
+
+	users, err := Open(env, "users", mdbx.Create, Uint64, JSON(user{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = env.Update(func(txn *mdbx.Txn) error {
+		for i, name := range []string{"ann", "bob", "cid", "dan"} {
+			if err := users.Put(txn, uint64(i+1), user{Name: name, Age: 20 + i}, 0); err != nil {
+				return err
+			}
+		}
+		if err := users.Put(txn, "five", user{}, 0); err == nil {
+			t.Error("put of a string key")
+		}
+		if err := users.Delete(txn, uint64(3)); err != nil {
+			return err
+		}
+		if err := users.Delete(txn, uint64(3)); !mdbx.IsNotFound(err) {
+			t.Errorf("delete of a missing key: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = env.View(func(txn *mdbx.Txn) error {
+		v, err := users.Get(txn, uint64(2))
+		if err != nil {
+			return err
+		}
+		if want := (user{Name: "bob", Age: 21}); !reflect.DeepEqual(v, want) {
+			t.Errorf("user 2: %#v", v)
+		}
+		if _, err := users.Get(txn, uint64(3)); !mdbx.IsNotFound(err) {
+			t.Errorf("get of a deleted key: %v", err)
+		}
+		if txn.RawRead {
+			t.Error("RawRead left set")
+		}
+
+		start, err := users.EncodeKey(uint64(2))
+		if err != nil {
+			return err
+		}
+		var names []string
+		err = users.Scan(txn, mdbx.ScanOptions{Start: start}, func(k, v interface{}) (bool, error) {
+			if txn.RawRead {
+				t.Error("RawRead set in the scan callback")
+			}
+			names = append(names, v.(user).Name)
+			return true, nil
+		})
+		if want := []string{"bob", "dan"}; !reflect.DeepEqual(names, want) {
+			t.Errorf("scanned %q, want %q", names, want)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTable_Raw(t *testing.T) {
+	env := openEnv(t)
+
+	blobs, err := Open(env, "blobs", mdbx.Create, String, Raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = env.Update(func(txn *mdbx.Txn) error {
+		return blobs.Put(txn, "k", []byte("value"), 0)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var copied, raw interface{}
+	err = env.View(func(txn *mdbx.Txn) (err error) {
+		if copied, err = blobs.Get(txn, "k"); err != nil {
+			return err
+		}
+		txn.RawRead = true
+		if raw, err = blobs.Get(txn, "k"); err != nil {
+			return err
+		}
+		if &copied.([]byte)[0] == &raw.([]byte)[0] {
+			t.Error("value shared without RawRead")
+		}
+		txn.RawRead = false
+		err = blobs.Scan(txn, mdbx.ScanOptions{Raw: true}, func(k, v interface{}) (bool, error) {
+			if &v.([]byte)[0] != &raw.([]byte)[0] {
+				t.Error("value copied with ScanOptions.Raw")
+			}
+			return true, nil
+		})
+		if err != nil {
+			return err
+		}
+		txn.RawRead = true
+		return blobs.Scan(txn, mdbx.ScanOptions{}, func(k, v interface{}) (bool, error) {
+			if &v.([]byte)[0] != &raw.([]byte)[0] {
+				t.Error("value copied with RawRead")
+			}
+			return true, nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(copied.([]byte)) != "value" {
+		t.Errorf("value %q", copied)
+	}
+}
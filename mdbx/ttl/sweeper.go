@@ -0,0 +1,101 @@
+package ttl
+
+import (
+	"sync"
+	"time"
+
+	"github.com/xzfkiller/mdbx-go/mdbx"
+)
+
+// Defaults of SweeperOptions.
+const (
+	DefaultSweepInterval = time.Minute
+	DefaultBatchSize     = 1000
+)
+
+// SweeperOptions configures a Sweeper.
+type SweeperOptions struct {
+	// Interval is the time between sweeps, DefaultSweepInterval if zero.
+	Interval time.Duration
+
+	// BatchSize is the maximum number of keys deleted in one write
+	// transaction, DefaultBatchSize if zero.  A sweep continues with further
+	// transactions until no expired keys are left, so that other writers are
+	// not blocked for long.
+	BatchSize int
+
+	// OnError is called with the errors of sweeps, which are otherwise
+	// ignored.  The next sweep starts at the next interval.
+	OnError func(error)
+}
+
+// Sweeper periodically deletes the expired keys of a Store.  The Sweeper
+// must be stopped before the environment is closed.
+type Sweeper struct {
+	s    *Store
+	opts SweeperOptions
+	stop sync.Once
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// StartSweeper starts a goroutine sweeping the expired keys of s.
+func (s *Store) StartSweeper(opts SweeperOptions) *Sweeper {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultSweepInterval
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	sw := &Sweeper{
+		s:    s,
+		opts: opts,
+		done: make(chan struct{}),
+	}
+	sw.wg.Add(1)
+	go sw.loop()
+	return sw
+}
+
+func (sw *Sweeper) loop() {
+	defer sw.wg.Done()
+	tick := time.NewTicker(sw.opts.Interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-sw.done:
+			return
+		case <-tick.C:
+			if err := sw.sweep(); err != nil && sw.opts.OnError != nil {
+				sw.opts.OnError(err)
+			}
+		}
+	}
+}
+
+// sweep deletes expired keys in batches until none are left or the Sweeper
+// is stopped.
+func (sw *Sweeper) sweep() error {
+	for {
+		var n int
+		err := sw.s.env.Update(func(txn *mdbx.Txn) (err error) {
+			n, err = sw.s.Sweep(txn, sw.opts.BatchSize)
+			return err
+		})
+		if err != nil || n < sw.opts.BatchSize {
+			return err
+		}
+		select {
+		case <-sw.done:
+			return nil
+		default:
+		}
+	}
+}
+
+// Stop stops the Sweeper and waits for a running sweep to finish its current
+// transaction.  Stop may be called more than once.
+func (sw *Sweeper) Stop() {
+	sw.stop.Do(func() { close(sw.done) })
+	sw.wg.Wait()
+}
@@ -0,0 +1,331 @@
+/*
+Package ttl adds expiring keys to the databases of an mdbx.Env.  The expiry
+of each key written by PutWithTTL is recorded in a companion database, keyed
+by expiry and key so that expired keys are found in order.  Reads through a
+Store treat expired keys as not found, and a Sweeper deletes them in the
+background.
+
+	store, err := ttl.Open(env, "sessions_ttl")
+	if err != nil {
+		return err
+	}
+	sessions, err := store.DBI("sessions", mdbx.Create)
+	if err != nil {
+		return err
+	}
+	sweeper := store.StartSweeper(ttl.SweeperOptions{Interval: time.Minute})
+	defer sweeper.Stop()
+
+	err = env.Update(func(txn *mdbx.Txn) error {
+		return store.PutWithTTL(txn, sessions, id, session, 30*time.Minute)
+	})
+
+The companion database refers to databases by name, so databases with
+expiring keys are opened through Store.DBI.  Keys with an expiry must only be
+written and deleted through the Store, otherwise their expiry may delete a
+newer value.
+*/
+package ttl
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/xzfkiller/mdbx-go/mdbx"
+)
+
+// ErrUnknownDBI is returned for a database handle which was not returned by
+// Store.DBI.
+var ErrUnknownDBI = errors.New("ttl: database was not opened by the store")
+
+// Prefixes of the two kinds of items in the companion database.
+const (
+	tagExpiry = 'e' // tagExpiry, expiry, name, key -> empty
+	tagKey    = 'k' // tagKey, name, key -> expiry
+)
+
+// Store reads and writes keys with an expiry.  The names of the databases
+// opened by DBI are guarded by a mutex, so goroutines may share a Store while
+// each of them passes its own transactions to its methods.
+type Store struct {
+	env *mdbx.Env
+	dbi mdbx.DBI
+	now func() time.Time
+
+	mu    sync.Mutex
+	names map[mdbx.DBI]string
+	dbis  map[string]mdbx.DBI
+}
+
+// New returns a Store recording expiries in the companion database dbi.
+func New(env *mdbx.Env, dbi mdbx.DBI) *Store {
+	return &Store{
+		env:   env,
+		dbi:   dbi,
+		now:   time.Now,
+		names: map[mdbx.DBI]string{},
+		dbis:  map[string]mdbx.DBI{},
+	}
+}
+
+// Open returns a Store recording expiries in the named companion database of
+// env.  The companion database is created if needed in a write transaction
+// begun by env.DBI, which would wait forever for an Update of the caller.
+func Open(env *mdbx.Env, name string) (*Store, error) {
+	dbi, err := env.DBI(name, mdbx.Create)
+	if err != nil {
+		return nil, err
+	}
+	return New(env, dbi), nil
+}
+
+// DBI returns a handle to the named database, opened with env.DBI, for use
+// with the methods of s.  The Store records the name of the database, by which
+// the companion database refers to it, so every database with expiring keys
+// is opened through DBI before it is written.
+func (s *Store) DBI(name string, flags uint) (mdbx.DBI, error) {
+	dbi, err := s.env.DBI(name, flags)
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	s.names[dbi] = name
+	s.dbis[name] = dbi
+	s.mu.Unlock()
+	return dbi, nil
+}
+
+func (s *Store) name(dbi mdbx.DBI) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name, ok := s.names[dbi]
+	if !ok {
+		return "", ErrUnknownDBI
+	}
+	return name, nil
+}
+
+func appendName(b []byte, name string) []byte {
+	var n [2]byte
+	binary.BigEndian.PutUint16(n[:], uint16(len(name)))
+	b = append(b, n[:]...)
+	return append(b, name...)
+}
+
+func keyKey(name string, key []byte) []byte {
+	b := appendName([]byte{tagKey}, name)
+	return append(b, key...)
+}
+
+func expiryKey(expiry uint64, name string, key []byte) []byte {
+	b := make([]byte, 9, 11+len(name)+len(key))
+	b[0] = tagExpiry
+	binary.BigEndian.PutUint64(b[1:], expiry)
+	b = appendName(b, name)
+	return append(b, key...)
+}
+
+// parseExpiryKey returns the name and key of an expiry item.
+func parseExpiryKey(b []byte) (name string, key []byte, ok bool) {
+	if len(b) < 11 {
+		return "", nil, false
+	}
+	n := int(binary.BigEndian.Uint16(b[9:]))
+	if len(b) < 11+n {
+		return "", nil, false
+	}
+	return string(b[11 : 11+n]), b[11+n:], true
+}
+
+func unixNano(t time.Time) uint64 {
+	return uint64(t.UnixNano())
+}
+
+// expiry returns the expiry of key in the database name, which is zero if
+// key has none.
+func (s *Store) expiry(txn *mdbx.Txn, name string, key []byte) (uint64, error) {
+	v, err := txn.Get(s.dbi, keyKey(name, key))
+	if mdbx.IsNotFound(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(v) != 8 {
+		return 0, errors.New("ttl: invalid expiry")
+	}
+	return binary.BigEndian.Uint64(v), nil
+}
+
+// clear removes the expiry of key in the database name.
+func (s *Store) clear(txn *mdbx.Txn, name string, key []byte) error {
+	expiry, err := s.expiry(txn, name, key)
+	if err != nil || expiry == 0 {
+		return err
+	}
+	if err = txn.Del(s.dbi, keyKey(name, key), nil); err != nil {
+		return err
+	}
+	return txn.Del(s.dbi, expiryKey(expiry, name, key), nil)
+}
+
+// PutWithTTL stores val under key in the database dbi, to expire after ttl.
+// It replaces any previous expiry of key.  ttl must be positive.
+func (s *Store) PutWithTTL(txn *mdbx.Txn, dbi mdbx.DBI, key, val []byte, ttl time.Duration) error {
+	if ttl <= 0 {
+		return errors.New("ttl: non-positive ttl")
+	}
+	name, err := s.name(dbi)
+	if err != nil {
+		return err
+	}
+	if err = s.clear(txn, name, key); err != nil {
+		return err
+	}
+	if err = txn.Put(dbi, key, val, 0); err != nil {
+		return err
+	}
+	expiry := unixNano(s.now().Add(ttl))
+	var v [8]byte
+	binary.BigEndian.PutUint64(v[:], expiry)
+	if err = txn.Put(s.dbi, keyKey(name, key), v[:], 0); err != nil {
+		return err
+	}
+	return txn.Put(s.dbi, expiryKey(expiry, name, key), nil, 0)
+}
+
+// Put stores val under key in the database dbi, passing flags to Txn.Put,
+// and removes any expiry of key.
+func (s *Store) Put(txn *mdbx.Txn, dbi mdbx.DBI, key, val []byte, flags uint) error {
+	name, err := s.name(dbi)
+	if err != nil {
+		return err
+	}
+	if err = txn.Put(dbi, key, val, flags); err != nil {
+		return err
+	}
+	return s.clear(txn, name, key)
+}
+
+// Get returns the value of key in the database dbi, following the RawRead
+// rules of Txn.Get.  If key is not found or has expired Get returns an error
+// for which mdbx.IsNotFound is true.
+func (s *Store) Get(txn *mdbx.Txn, dbi mdbx.DBI, key []byte) ([]byte, error) {
+	name, err := s.name(dbi)
+	if err != nil {
+		return nil, err
+	}
+	expiry, err := s.expiry(txn, name, key)
+	if err != nil {
+		return nil, err
+	}
+	if expiry != 0 && expiry <= unixNano(s.now()) {
+		return nil, &mdbx.OpError{Op: "mdbx_get", Errno: mdbx.NotFound}
+	}
+	return txn.Get(dbi, key)
+}
+
+// ExpiresAt returns the time at which key in the database dbi expires, which
+// is the zero time if key has no expiry.
+func (s *Store) ExpiresAt(txn *mdbx.Txn, dbi mdbx.DBI, key []byte) (time.Time, error) {
+	name, err := s.name(dbi)
+	if err != nil {
+		return time.Time{}, err
+	}
+	expiry, err := s.expiry(txn, name, key)
+	if err != nil || expiry == 0 {
+		return time.Time{}, err
+	}
+	return time.Unix(0, int64(expiry)), nil
+}
+
+// Del deletes key and its expiry from the database dbi.  If key is not found
+// or has expired Del returns an error for which mdbx.IsNotFound is true, like
+// Get.  An expired key which has not been swept yet is deleted all the same.
+func (s *Store) Del(txn *mdbx.Txn, dbi mdbx.DBI, key []byte) error {
+	name, err := s.name(dbi)
+	if err != nil {
+		return err
+	}
+	expiry, err := s.expiry(txn, name, key)
+	if err != nil {
+		return err
+	}
+	if err = txn.Del(dbi, key, nil); err != nil {
+		return err
+	}
+	if err = s.clear(txn, name, key); err != nil {
+		return err
+	}
+	if expiry != 0 && expiry <= unixNano(s.now()) {
+		return &mdbx.OpError{Op: "mdbx_del", Errno: mdbx.NotFound}
+	}
+	return nil
+}
+
+// open returns the handle of the named database for a sweep, opening it in
+// txn if it was not opened by DBI.  dbis caches the handles of a sweep.
+func (s *Store) open(txn *mdbx.Txn, name string, dbis map[string]mdbx.DBI) (mdbx.DBI, error) {
+	if dbi, ok := dbis[name]; ok {
+		return dbi, nil
+	}
+	s.mu.Lock()
+	dbi, ok := s.dbis[name]
+	s.mu.Unlock()
+	if !ok {
+		var err error
+		if name == "" {
+			dbi, err = txn.OpenRoot(0)
+		} else {
+			dbi, err = txn.OpenDBI(name, 0)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	dbis[name] = dbi
+	return dbi, nil
+}
+
+// Sweep deletes at most limit expired keys, oldest first, in txn and returns
+// the number of keys deleted.  A limit of zero means no limit.
+func (s *Store) Sweep(txn *mdbx.Txn, limit int) (int, error) {
+	opts := mdbx.ScanOptions{
+		Prefix:   []byte{tagExpiry},
+		End:      expiryKey(unixNano(s.now())+1, "", nil),
+		Limit:    limit,
+		KeysOnly: true,
+	}
+	var expired [][]byte
+	err := txn.Scan(s.dbi, opts, func(k, _ []byte) (bool, error) {
+		expired = append(expired, append([]byte(nil), k...))
+		return true, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	dbis := map[string]mdbx.DBI{}
+	for _, k := range expired {
+		name, key, ok := parseExpiryKey(k)
+		if !ok {
+			return 0, errors.New("ttl: invalid expiry key")
+		}
+		dbi, err := s.open(txn, name, dbis)
+		if err == nil {
+			err = txn.Del(dbi, key, nil)
+		}
+		if err != nil && !mdbx.IsNotFound(err) {
+			return 0, err
+		}
+		if err = txn.Del(s.dbi, keyKey(name, key), nil); err != nil && !mdbx.IsNotFound(err) {
+			return 0, err
+		}
+		if err = txn.Del(s.dbi, k, nil); err != nil {
+			return 0, err
+		}
+	}
+	return len(expired), nil
+}
@@ -0,0 +1,219 @@
+package ttl
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/xzfkiller/mdbx-go/mdbx"
+)
+
+// openEnv opens an environment in a temporary directory, both of which are
+// removed when the test ends.
+func openEnv(t *testing.T) *mdbx.Env {
+	path, err := ioutil.TempDir("", "ttl_test")
+	if err != nil {
+		t.Fatalf("Cannot create temporary directory: %s", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(path) })
+	env, err := mdbx.NewEnv()
+	if err != nil {
+		t.Fatalf("Cannot create environment: %s", err)
+	}
+	t.Cleanup(func() { env.Close() })
+	if err = env.SetMaxDBs(4); err != nil {
+		t.Fatalf("Cannot set max dbs: %s", err)
+	}
+	if err = env.Open(path); err != nil {
+		t.Fatalf("Cannot open environment: %s", err)
+	}
+	return env
+}
+
+func setup(t *testing.T) (*mdbx.Env, *Store, mdbx.DBI) {
+	env := openEnv(t)
+	s, err := Open(env, "expiries")
+	if err != nil {
+		t.Fatalf("Cannot open store: %s", err)
+	}
+	dbi, err := s.DBI("sessions", mdbx.Create)
+	if err != nil {
+		t.Fatalf("Cannot open database: %s", err)
+	}
+	return env, s, dbi
+}
+
+// entries returns the number of items in dbi.
+func entries(t *testing.T, txn *mdbx.Txn, dbi mdbx.DBI) uint64 {
+	stat, err := txn.Stat(dbi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stat.Entries
+}
+
+func TestStore(t *testing.T) {
+	env, s, dbi := setup(t)
+	now := time.Unix(1600000000, 0)
+	s.now = func() time.Time { return now }
+
+	err := env.Update(func(txn *mdbx.Txn) error {
+		if err := s.PutWithTTL(txn, dbi, []byte("a"), []byte("1"), time.Minute); err != nil {
+			return err
+		}
+		if err := s.PutWithTTL(txn, dbi, []byte("a"), []byte("2"), time.Hour); err != nil {
+			return err
+		}
+		if err := s.PutWithTTL(txn, dbi, []byte("b"), []byte("3"), time.Minute); err != nil {
+			return err
+		}
+		if n := entries(t, txn, s.dbi); n != 4 {
+			t.Errorf("%d items in the companion database, want 4", n)
+		}
+		exp, err := s.ExpiresAt(txn, dbi, []byte("a"))
+		if err != nil || !exp.Equal(now.Add(time.Hour)) {
+			t.Errorf("ExpiresAt: %v %v", exp, err)
+		}
+
+		now = now.Add(2 * time.Minute)
+		v, err := s.Get(txn, dbi, []byte("a"))
+		if err != nil || string(v) != "2" {
+			t.Errorf("Get: %q %v", v, err)
+		}
+		if _, err = s.Get(txn, dbi, []byte("b")); !mdbx.IsNotFound(err) {
+			t.Errorf("Get of an expired key: %v", err)
+		}
+		if _, err = txn.Get(dbi, []byte("b")); err != nil {
+			t.Errorf("expired key not kept until swept: %v", err)
+		}
+
+		// Put without a TTL and Del remove the expiry
+		if err = s.Put(txn, dbi, []byte("b"), []byte("4"), 0); err != nil {
+			return err
+		}
+		if v, err = s.Get(txn, dbi, []byte("b")); err != nil || string(v) != "4" {
+			t.Errorf("Get after Put: %q %v", v, err)
+		}
+		if exp, err = s.ExpiresAt(txn, dbi, []byte("b")); err != nil || !exp.IsZero() {
+			t.Errorf("ExpiresAt after Put: %v %v", exp, err)
+		}
+		if err = s.Del(txn, dbi, []byte("a")); err != nil {
+			return err
+		}
+		if err = s.Del(txn, dbi, []byte("a")); !mdbx.IsNotFound(err) {
+			t.Errorf("Del of a missing key: %v", err)
+		}
+
+		// an expired key is deleted but reported as not found, like by Get
+		if err = s.PutWithTTL(txn, dbi, []byte("d"), []byte("5"), time.Minute); err != nil {
+			return err
+		}
+		now = now.Add(2 * time.Minute)
+		if err = s.Del(txn, dbi, []byte("d")); !mdbx.IsNotFound(err) {
+			t.Errorf("Del of an expired key: %v", err)
+		}
+		if _, err = txn.Get(dbi, []byte("d")); !mdbx.IsNotFound(err) {
+			t.Errorf("expired key kept by Del: %v", err)
+		}
+		if n := entries(t, txn, s.dbi); n != 0 {
+			t.Errorf("%d items left in the companion database", n)
+		}
+
+		if err = s.PutWithTTL(txn, dbi, []byte("c"), nil, 0); err == nil {
+			t.Error("PutWithTTL with a zero ttl")
+		}
+		if _, err = s.Get(txn, s.dbi, []byte("c")); err != ErrUnknownDBI {
+			t.Errorf("Get of an unknown database: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStore_Sweep(t *testing.T) {
+	env, s, dbi := setup(t)
+	now := time.Unix(1600000000, 0)
+	s.now = func() time.Time { return now }
+
+	err := env.Update(func(txn *mdbx.Txn) error {
+		for i := 1; i <= 5; i++ {
+			k := []byte(fmt.Sprint(i))
+			if err := s.PutWithTTL(txn, dbi, k, k, time.Duration(6-i)*time.Second); err != nil {
+				return err
+			}
+		}
+
+		now = now.Add(3 * time.Second)
+		n, err := s.Sweep(txn, 2)
+		if err != nil || n != 2 {
+			t.Errorf("Sweep: %d %v", n, err)
+		}
+		for k, gone := range map[string]bool{"5": true, "4": true, "3": false, "1": false} {
+			if _, err := txn.Get(dbi, []byte(k)); mdbx.IsNotFound(err) != gone {
+				t.Errorf("%s: %v", k, err)
+			}
+		}
+		if n, err = s.Sweep(txn, 0); err != nil || n != 1 {
+			t.Errorf("Sweep: %d %v", n, err)
+		}
+		if n := entries(t, txn, dbi); n != 2 {
+			t.Errorf("%d items left, want 2", n)
+		}
+		if n := entries(t, txn, s.dbi); n != 4 {
+			t.Errorf("%d items left in the companion database, want 4", n)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSweeper(t *testing.T) {
+	env, s, dbi := setup(t)
+
+	err := env.Update(func(txn *mdbx.Txn) error {
+		for i := 0; i < 10; i++ {
+			k := []byte(fmt.Sprint(i))
+			if err := s.PutWithTTL(txn, dbi, k, k, time.Millisecond); err != nil {
+				return err
+			}
+		}
+		return s.PutWithTTL(txn, dbi, []byte("kept"), nil, time.Hour)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sw := s.StartSweeper(SweeperOptions{
+		Interval:  5 * time.Millisecond,
+		BatchSize: 3,
+		OnError:   func(err error) { t.Error(err) },
+	})
+	defer sw.Stop()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var n uint64
+		err = env.View(func(txn *mdbx.Txn) error {
+			n = entries(t, txn, dbi)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d items left", n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	sw.Stop()
+	sw.Stop()
+}
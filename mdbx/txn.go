@@ -9,11 +9,27 @@ package mdbx
 import "C"
 
 import (
+	"context"
 	"log"
 	"runtime"
+	"strconv"
+	"strings"
 	"unsafe"
 )
 
+// The TxnTry flag only applies to write transactions.  The TxnNoSync and
+// TxnNoMetaSync flags weaken the durability of a single write transaction the
+// same way the corresponding Env flags do for all of them.
+const (
+	// Flags for Env.BeginTxn and Env.RunTxn.
+	//
+	// See mdbx_txn_begin.
+
+	TxnTry        = C.MDBX_TXN_TRY        // Do not block when starting a write transaction.
+	TxnNoSync     = C.MDBX_TXN_NOSYNC     // Don't fsync after commit of this transaction.
+	TxnNoMetaSync = C.MDBX_TXN_NOMETASYNC // Don't fsync metapage after commit of this transaction.
+)
+
 // This flags are used exclusively for Txn.OpenDBI and Txn.OpenRoot.  The
 // Create flag must always be supplied when opening a non-root DBI for the
 // first time.
@@ -28,9 +44,9 @@ const (
 	Create     = C.MDBX_CREATE     // Create DB if not already existing.
 )
 
-// The MDB_MULTIPLE and MDB_RESERVE flags are special and do not fit the
+// The MDBX_MULTIPLE and MDBX_RESERVE flags are special and do not fit the
 // calling pattern of other calls to Put.  They are not exported because they
-// require special methods, PutMultiple and PutReserve in which the flag is
+// require special methods, Cursor.PutMulti and PutReserve in which the flag is
 // implied and does not need to be passed.
 const (
 	// Flags for Txn.Put and Cursor.Put.
@@ -42,8 +58,13 @@ const (
 	NoOverwrite = C.MDBX_NOOVERWRITE // Store a new key-value pair only if key is not present.
 	Append      = C.MDBX_APPEND      // Append an item to the database.
 	AppendDup   = C.MDBX_APPENDDUP   // Append an item to the database (DupSort).
+	AllDups     = C.MDBX_ALLDUPS     // Replace or delete all values of the current key (DupSort).
 )
 
+// StopOnError is a flag for Txn.PutMany and Txn.DelMany which stops the batch
+// at the first item that fails.  It is not passed to MDBX.
+const StopOnError = C.MDBXGO_STOP_ON_ERROR
+
 const (
 	valSizeBits = 31
 	valMaxSize  = 1<<valSizeBits - 1
@@ -81,6 +102,10 @@ type Txn struct {
 	key  *C.MDBX_val
 	val  *C.MDBX_val
 
+	// ctx is the context of a transaction created by ViewContext or
+	// UpdateContext, or of its parent.
+	ctx context.Context
+
 	errLogf func(format string, v ...interface{})
 }
 
@@ -113,6 +138,7 @@ func beginTxn(env *Env, parent *Txn, flags uint) (*Txn, error) {
 		ptxn = parent._txn
 		txn.key = parent.key
 		txn.val = parent.val
+		txn.ctx = parent.ctx
 	}
 	ret := C.mdbx_txn_begin(env._env, ptxn, C.MDBX_txn_flags_t(flags), &txn._txn)
 	if ret != success {
@@ -307,6 +333,33 @@ func (txn *Txn) renew() error {
 	return operrno("mdbx_txn_renew", ret)
 }
 
+// Break marks txn as unusable so that subsequent operations on it fail fast
+// with BadTxn, without terminating it.  The transaction must still be
+// terminated as usual.  Break may be called concurrently with other methods of
+// a readonly txn, such as from a supervisor goroutine interrupting a long read,
+// but the caller must ensure txn is not terminated meanwhile.
+//
+// See mdbx_txn_break.
+func (txn *Txn) Break() error {
+	ret := C.mdbx_txn_break(txn._txn)
+	return operrno("mdbx_txn_break", ret)
+}
+
+// Straggler returns how far a readonly txn lags behind the most recent
+// commit.  The lag is the number of transactions committed after txn was
+// started, percent is the percentage of the database pages in use.  For a
+// write transaction lag is always zero.
+//
+// See mdbx_txn_straggler.
+func (txn *Txn) Straggler() (lag int, percent int, err error) {
+	var _lag, _percent C.int
+	ret := C.mdbxgo_txn_straggler(txn._txn, &_lag, &_percent)
+	if ret != success {
+		return 0, 0, operrno("mdbx_txn_straggler", ret)
+	}
+	return int(_lag), int(_percent), nil
+}
+
 // OpenDBI opens a named database in the environment.  An error is returned if
 // name is empty.  The DBI returned by OpenDBI can be used in other
 // transactions but not before Txn has terminated.
@@ -349,12 +402,111 @@ func (txn *Txn) openDBI(cname *C.char, flags uint) (DBI, error) {
 	return DBI(dbi), operrno("mdbx_dbi_open", ret)
 }
 
+// DBIState describes the state of a database handle within a transaction.
+//
+// See MDBX_dbi_state_t.
+type DBIState uint
+
+// State bits returned by Txn.DBIFlags.
+const (
+	DBIDirty   DBIState = C.MDBX_DBI_DIRTY // The database was written in the transaction.
+	DBIStale   DBIState = C.MDBX_DBI_STALE // The named database record is older than the transaction.
+	DBIFresh   DBIState = C.MDBX_DBI_FRESH // The handle was opened in the transaction.
+	DBICreated DBIState = C.MDBX_DBI_CREAT // The named database was created in the transaction.
+)
+
+// DBIFlags returns the flags dbi was opened with, such as DupSort, and its
+// state within txn.
+//
+// See mdbx_dbi_flags_ex.
+func (txn *Txn) DBIFlags(dbi DBI) (flags uint, state DBIState, err error) {
+	var _flags, _state C.uint
+	ret := C.mdbx_dbi_flags_ex(txn._txn, C.MDBX_dbi(dbi), &_flags, &_state)
+	if ret != success {
+		return 0, 0, operrno("mdbx_dbi_flags_ex", ret)
+	}
+	return uint(_flags), DBIState(_state), nil
+}
+
+// Flags returns the flags dbi was opened with, like DBIFlags without the
+// state.
+//
+// See mdbx_dbi_flags_ex.
+func (txn *Txn) Flags(dbi DBI) (uint, error) {
+	flags, _, err := txn.DBIFlags(dbi)
+	return flags, err
+}
+
+// Stat returns statistics about database dbi as seen by txn.
+//
+// See mdbx_dbi_stat.
+func (txn *Txn) Stat(dbi DBI) (*Stat, error) {
+	var _stat C.MDBX_stat
+	ret := C.mdbx_dbi_stat(txn._txn, C.MDBX_dbi(dbi), &_stat, C.size_t(unsafe.Sizeof(_stat)))
+	if ret != success {
+		return nil, operrno("mdbx_dbi_stat", ret)
+	}
+	return newStat(&_stat), nil
+}
+
+// dbRecordSize is the size of the record describing a named database in the
+// root database (sizeof(MDBX_db)).
+const dbRecordSize = 48
+
+// ListDBIs returns the names of the named databases in the environment, in
+// order, by iterating the root database.  Items an application stored in the
+// root database besides named databases are skipped.  Each named database is
+// confirmed by opening it, so the environment needs enough MaxDBs for a handle
+// to every named database, and handles opened by ListDBIs stay open like the
+// ones of OpenDBI.
+func (txn *Txn) ListDBIs() ([]string, error) {
+	root, err := txn.OpenRoot(0)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := txn.OpenCursor(root)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close()
+
+	var names []string
+	for {
+		k, v, err := cur.Get(nil, nil, Next)
+		if IsNotFound(err) {
+			return names, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		// names of databases are C strings
+		name := string(k)
+		if len(v) != dbRecordSize || len(name) == 0 || strings.IndexByte(name, 0) >= 0 {
+			continue
+		}
+		// only the node flags, which mdbx_dbi_open checks, tell a named
+		// database from a plain value of the same size
+		_, err = txn.OpenDBI(name, C.MDBX_DB_ACCEDE)
+		if IsErrno(err, Incompatible) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+}
+
 // Drop empties the database if del is false.  Drop deletes and closes the
-// database if del is true.
+// database if del is true, in which case a handle cached by Env.DBI is
+// forgotten.
 //
 // See mdbx_drop.
 func (txn *Txn) Drop(dbi DBI, del bool) error {
 	ret := C.mdbx_drop(txn._txn, C.MDBX_dbi(dbi), cbool(del))
+	if del && ret == success {
+		txn.env.dbis.forget(dbi)
+	}
 	return operrno("mdbx_drop", ret)
 }
 
@@ -389,6 +541,18 @@ func (txn *Txn) subFlag(flags uint, fn TxnOp) error {
 	return sub.commit()
 }
 
+// OpenCursor allocates and initializes a Cursor to database dbi.  The cursor
+// must be closed when it is no longer needed.
+//
+// See mdbx_cursor_open.
+func (txn *Txn) OpenCursor(dbi DBI) (*Cursor, error) {
+	cur, err := openCursor(txn, dbi)
+	if cur != nil && txn.readonly {
+		runtime.SetFinalizer(cur, (*Cursor).close)
+	}
+	return cur, err
+}
+
 var eb = []byte{0}
 
 func valBytes(b []byte) ([]byte, int) {
@@ -419,6 +583,19 @@ func (txn *Txn) bytes(val *C.MDBX_val) []byte {
 //
 // See mdbx_get.
 func (txn *Txn) Get(dbi DBI, key []byte) ([]byte, error) {
+	return txn.get(dbi, key, txn.RawRead)
+}
+
+// GetRaw is like Get but the returned slice always references the memory
+// map, whatever txn.RawRead is set to.  It must not be modified nor accessed
+// after txn has terminated.
+//
+// See mdbx_get.
+func (txn *Txn) GetRaw(dbi DBI, key []byte) ([]byte, error) {
+	return txn.get(dbi, key, true)
+}
+
+func (txn *Txn) get(dbi DBI, key []byte, raw bool) ([]byte, error) {
 	kdata, kn := valBytes(key)
 	ret := C.mdbxgo_mdb_get(
 		txn._txn, C.MDBX_dbi(dbi),
@@ -430,11 +607,56 @@ func (txn *Txn) Get(dbi DBI, key []byte) ([]byte, error) {
 		*txn.val = C.MDBX_val{}
 		return nil, err
 	}
-	b := txn.bytes(txn.val)
+	var b []byte
+	if raw {
+		b = getBytes(txn.val)
+	} else {
+		b = getBytesCopy(txn.val)
+	}
 	*txn.val = C.MDBX_val{}
 	return b, nil
 }
 
+// GetMany retrieves the items of keys from database dbi and calls fn with the
+// index of each key and its value or error, in order.  All lookups are done in
+// a single cgo call, which makes GetMany much cheaper than calling Get for
+// every key when there are many of them.  Values follow the same RawRead rules
+// as Get.
+//
+// See mdbx_get.
+func (txn *Txn) GetMany(dbi DBI, keys [][]byte, fn func(i int, val []byte, err error)) {
+	n := len(keys)
+	if n == 0 {
+		return
+	}
+	size := 0
+	for _, k := range keys {
+		size += len(k)
+	}
+	kdata := make([]byte, 0, size)
+	koffs := make([]C.size_t, n)
+	for i, k := range keys {
+		kdata = append(kdata, k...)
+		koffs[i] = C.size_t(len(kdata))
+	}
+	kdata, _ = valBytes(kdata)
+	vals := make([]C.MDBX_val, n)
+	rets := make([]C.int, n)
+
+	C.mdbxgo_mdb_get_many(
+		txn._txn, C.MDBX_dbi(dbi),
+		(*C.char)(unsafe.Pointer(&kdata[0])), &koffs[0], C.size_t(n),
+		&vals[0], &rets[0],
+	)
+	for i := range keys {
+		if err := operrno("mdbx_get", rets[i]); err != nil {
+			fn(i, nil, err)
+			continue
+		}
+		fn(i, txn.bytes(&vals[i]), nil)
+	}
+}
+
 func (txn *Txn) putNilKey(dbi DBI, flags uint) error {
 	// mdbx_put with an empty key will always fail
 	ret := C.mdbxgo_mdb_put2(txn._txn, C.MDBX_dbi(dbi), nil, 0, nil, 0, C.uint(flags))
@@ -463,8 +685,37 @@ func (txn *Txn) Put(dbi DBI, key []byte, val []byte, flags uint) error {
 	return operrno("mdbx_put", ret)
 }
 
-// Del deletes an item from database dbi.  Del ignores val unless dbi has the
-// DupSort flag.
+// PutReserve returns a []byte of length n that can be written to, potentially
+// avoiding a memcopy.  The returned byte slice is only valid in txn's thread,
+// before it has terminated or written again to dbi.
+//
+// See mdbx_put.
+func (txn *Txn) PutReserve(dbi DBI, key []byte, n int, flags uint) ([]byte, error) {
+	if n < 0 {
+		return nil, errNegSize
+	}
+	if len(key) == 0 {
+		return nil, txn.putNilKey(dbi, flags)
+	}
+	txn.val.iov_len = C.size_t(n)
+	ret := C.mdbxgo_mdb_put1(
+		txn._txn, C.MDBX_dbi(dbi),
+		(*C.char)(unsafe.Pointer(&key[0])), C.size_t(len(key)),
+		txn.val,
+		C.uint(flags|C.MDBX_RESERVE),
+	)
+	err := operrno("mdbx_put", ret)
+	if err != nil {
+		*txn.val = C.MDBX_val{}
+		return nil, err
+	}
+	b := getBytes(txn.val)
+	*txn.val = C.MDBX_val{}
+	return b, nil
+}
+
+// Del deletes an item from database dbi.  Del ignores val, deleting all the
+// items of key in a DupSort database, see DelwithVal to delete one of them.
 //
 // See mdbx_del.
 func (txn *Txn) Del(dbi DBI, key, val []byte) error {
@@ -476,9 +727,13 @@ func (txn *Txn) Del(dbi DBI, key, val []byte) error {
 	return operrno("mdbx_del", ret)
 }
 
+// DelwithVal deletes the item of key with the value val from database dbi.
+// In a DupSort database the other values of key are kept.
+//
+// See mdbx_del.
 func (txn *Txn) DelwithVal(dbi DBI, key, val []byte) error {
 	kdata, kn := valBytes(key)
-	vdata, vn := valBytes(key)
+	vdata, vn := valBytes(val)
 	ret := C.mdbxgo_mdb_del2(
 		txn._txn, C.MDBX_dbi(dbi),
 		(*C.char)(unsafe.Pointer(&kdata[0])), C.size_t(kn),
@@ -487,6 +742,138 @@ func (txn *Txn) DelwithVal(dbi DBI, key, val []byte) error {
 	return operrno("mdbx_del", ret)
 }
 
+// KV is a key-value pair stored by Txn.PutMany.
+type KV struct {
+	Key []byte
+	Val []byte
+}
+
+// BatchError is returned by Txn.PutMany and Txn.DelMany when some items of a
+// batch could not be applied.
+type BatchError struct {
+	Op   string  // The C function applied to every item
+	Errs []error // Errors indexed like the batch, nil for applied items
+	N    int     // Count of items attempted, less than len(Errs) after StopOnError
+}
+
+// Error implements the error interface.
+func (err *BatchError) Error() string {
+	var first error
+	failed := 0
+	for _, e := range err.Errs {
+		if e != nil {
+			if first == nil {
+				first = e
+			}
+			failed++
+		}
+	}
+	return strconv.Itoa(failed) + " of " + strconv.Itoa(len(err.Errs)) +
+		" batch items failed, first: " + first.Error()
+}
+
+// batchError collects the result codes of the first n items of a batch into a
+// *BatchError, or returns nil when all of them succeeded.
+func batchError(op string, rets []C.int, n int) error {
+	var errs []error
+	for i, ret := range rets[:n] {
+		if ret == success {
+			continue
+		}
+		if errs == nil {
+			errs = make([]error, len(rets))
+		}
+		errs[i] = operrno(op, ret)
+	}
+	if errs == nil {
+		return nil
+	}
+	return &BatchError{Op: op, Errs: errs, N: n}
+}
+
+// PutMany stores the items of kvs in database dbi, in order, with the same
+// flags as Put.  All items are applied in a single cgo call, which makes
+// PutMany much cheaper than calling Put for every item of a large batch.
+// The flags accepted are NoOverwrite, NoDupData, Append, AppendDup and
+// StopOnError, PutMany returns an error for which
+// IsErrnoSys(err, syscall.EINVAL) is true for any other flag.
+//
+// An item which fails does not stop the batch unless flags include
+// StopOnError.  If any item fails PutMany returns a *BatchError holding the
+// error of each item.  Errors which invalidate the transaction, such as
+// MapFull, cause the remaining items to fail as well.
+//
+// See mdbx_put.
+func (txn *Txn) PutMany(dbi DBI, kvs []KV, flags uint) error {
+	if flags&^(NoOverwrite|NoDupData|Append|AppendDup|StopOnError) != 0 {
+		return operrno("mdbx_put", C.MDBX_EINVAL)
+	}
+	n := len(kvs)
+	if n == 0 {
+		return nil
+	}
+	size := 0
+	for _, kv := range kvs {
+		size += len(kv.Key) + len(kv.Val)
+	}
+	data := make([]byte, 0, size)
+	offs := make([]C.size_t, 2*n)
+	for i, kv := range kvs {
+		data = append(data, kv.Key...)
+		offs[2*i] = C.size_t(len(data))
+		data = append(data, kv.Val...)
+		offs[2*i+1] = C.size_t(len(data))
+	}
+	data, _ = valBytes(data)
+	rets := make([]C.int, n)
+
+	done := C.mdbxgo_mdb_put_many(
+		txn._txn, C.MDBX_dbi(dbi),
+		(*C.char)(unsafe.Pointer(&data[0])), &offs[0], C.size_t(n),
+		C.uint(flags), &rets[0],
+	)
+	return batchError("mdbx_put", rets, int(done))
+}
+
+// DelMany deletes the items of keys from database dbi, in order, including all
+// of their values in a DupSort database.  All items are deleted in a single
+// cgo call.  The only flag accepted is StopOnError, mdbx_del takes no flags,
+// and DelMany returns an error for which IsErrnoSys(err, syscall.EINVAL) is
+// true for any other flag.
+//
+// If any item fails, e.g. because its key is not found, DelMany returns a
+// *BatchError holding the error of each item.
+//
+// See mdbx_del.
+func (txn *Txn) DelMany(dbi DBI, keys [][]byte, flags uint) error {
+	if flags&^StopOnError != 0 {
+		return operrno("mdbx_del", C.MDBX_EINVAL)
+	}
+	n := len(keys)
+	if n == 0 {
+		return nil
+	}
+	size := 0
+	for _, k := range keys {
+		size += len(k)
+	}
+	data := make([]byte, 0, size)
+	offs := make([]C.size_t, n)
+	for i, k := range keys {
+		data = append(data, k...)
+		offs[i] = C.size_t(len(data))
+	}
+	data, _ = valBytes(data)
+	rets := make([]C.int, n)
+
+	done := C.mdbxgo_mdb_del_many(
+		txn._txn, C.MDBX_dbi(dbi),
+		(*C.char)(unsafe.Pointer(&data[0])), &offs[0], C.size_t(n),
+		C.uint(flags), &rets[0],
+	)
+	return batchError("mdbx_del", rets, int(done))
+}
+
 func (txn *Txn) errf(format string, v ...interface{}) {
 	if txn.errLogf != nil {
 		txn.errLogf(format, v...)
@@ -0,0 +1,526 @@
+package mdbx
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestTxn_Straggler_Break(t *testing.T) {
+	env, _, teardown := setup(t)
+	defer teardown()
+
+	put := func(v string) {
+		err := env.Update(func(txn *Txn) error {
+			dbi, err := txn.OpenRoot(0)
+			if err != nil {
+				return err
+			}
+			return txn.Put(dbi, []byte("k"), []byte(v), 0)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	put("v0")
+
+	txn, err := env.BeginTxn(nil, Readonly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer txn.Abort()
+
+	put("v1")
+	put("v2")
+
+	lag, percent, err := txn.Straggler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lag != 2 {
+		t.Errorf("lag: %d (!= 2)", lag)
+	}
+	if percent <= 0 || percent > 100 {
+		t.Errorf("percent: %d", percent)
+	}
+
+	dbi, err := txn.OpenRoot(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error)
+	go func() { done <- txn.Break() }()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	_, err = txn.Get(dbi, []byte("k"))
+	if !IsErrno(err, BadTxn) {
+		t.Errorf("unexpected error after Break: %v", err)
+	}
+}
+
+func TestTxn_DBIFlags_ListDBIs(t *testing.T) {
+	env, _, teardown := setup(t)
+	defer teardown()
+
+	var dbi DBI
+	err := env.Update(func(txn *Txn) (err error) {
+		if _, err = txn.CreateDBI("b"); err != nil {
+			return err
+		}
+		// a plain value of the size of a named database record
+		root, err := txn.OpenRoot(0)
+		if err != nil {
+			return err
+		}
+		if err = txn.Put(root, []byte("ab"), make([]byte, dbRecordSize), 0); err != nil {
+			return err
+		}
+		dbi, err = txn.OpenDBI("a", Create|DupSort)
+		if err != nil {
+			return err
+		}
+		if err = txn.Put(dbi, []byte("k"), []byte("v"), 0); err != nil {
+			return err
+		}
+		flags, state, err := txn.DBIFlags(dbi)
+		if err != nil {
+			return err
+		}
+		if flags&DupSort == 0 {
+			t.Errorf("flags: %#x (missing DupSort)", flags)
+		}
+		if state&(DBIDirty|DBICreated) != DBIDirty|DBICreated {
+			t.Errorf("state: %#x (not dirty and created)", state)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = env.View(func(txn *Txn) error {
+		_, state, err := txn.DBIFlags(dbi)
+		if err != nil {
+			return err
+		}
+		if state&(DBIDirty|DBICreated) != 0 {
+			t.Errorf("state: %#x (dirty or created in a readonly txn)", state)
+		}
+		names, err := txn.ListDBIs()
+		if err != nil {
+			return err
+		}
+		if len(names) != 2 || names[0] != "a" || names[1] != "b" {
+			t.Errorf("names: %q", names)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTxn_GetMany(t *testing.T) {
+	env, _, teardown := setup(t)
+	defer teardown()
+
+	var dbi DBI
+	err := env.Update(func(txn *Txn) (err error) {
+		dbi, err = txn.OpenRoot(0)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < 10; i += 2 {
+			err = txn.Put(dbi, []byte(fmt.Sprintf("k%d", i)), []byte(fmt.Sprintf("v%d", i)), 0)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := [][]byte{[]byte("k0"), []byte("k1"), nil, []byte("k8"), []byte("k4")}
+	err = env.View(func(txn *Txn) error {
+		var seen []int
+		txn.GetMany(dbi, keys, func(i int, val []byte, err error) {
+			seen = append(seen, i)
+			switch string(keys[i]) {
+			case "k1":
+				if !IsNotFound(err) {
+					t.Errorf("key %q: unexpected error: %v", keys[i], err)
+				}
+			case "":
+				if err == nil {
+					t.Errorf("empty key: expected error")
+				}
+			default:
+				if err != nil {
+					t.Errorf("key %q: %v", keys[i], err)
+				} else if want := "v" + string(keys[i][1:]); string(val) != want {
+					t.Errorf("key %q: %q (!= %q)", keys[i], val, want)
+				}
+			}
+		})
+		if len(seen) != len(keys) {
+			t.Errorf("fn called %d times (!= %d)", len(seen), len(keys))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+const benchGetKeys = 500
+
+func setupBenchGet(b *testing.B) (*Env, DBI, [][]byte, func()) {
+	env, _, teardown := setup(b)
+	var dbi DBI
+	keys := make([][]byte, benchGetKeys)
+	err := env.Update(func(txn *Txn) (err error) {
+		dbi, err = txn.OpenRoot(0)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < 10*benchGetKeys; i++ {
+			k := []byte(fmt.Sprintf("key-%08d", i))
+			if err = txn.Put(dbi, k, []byte(fmt.Sprintf("val-%08d", i)), 0); err != nil {
+				return err
+			}
+			if i%10 == 0 {
+				keys[i/10] = k
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		teardown()
+		b.Fatal(err)
+	}
+	return env, dbi, keys, teardown
+}
+
+func BenchmarkTxn_Get_loop(b *testing.B) {
+	env, dbi, keys, teardown := setupBenchGet(b)
+	defer teardown()
+
+	b.ResetTimer()
+	err := env.View(func(txn *Txn) error {
+		txn.RawRead = true
+		for i := 0; i < b.N; i++ {
+			for _, k := range keys {
+				if _, err := txn.Get(dbi, k); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+}
+
+func BenchmarkTxn_GetMany(b *testing.B) {
+	env, dbi, keys, teardown := setupBenchGet(b)
+	defer teardown()
+
+	b.ResetTimer()
+	err := env.View(func(txn *Txn) (err error) {
+		txn.RawRead = true
+		for i := 0; i < b.N; i++ {
+			txn.GetMany(dbi, keys, func(i int, val []byte, e error) {
+				if e != nil {
+					err = e
+				}
+			})
+		}
+		return err
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+}
+
+func TestTxn_PutMany_DelMany(t *testing.T) {
+	env, _, teardown := setup(t)
+	defer teardown()
+
+	err := env.Update(func(txn *Txn) error {
+		dbi, err := txn.OpenRoot(0)
+		if err != nil {
+			return err
+		}
+		kvs := []KV{
+			{[]byte("a"), []byte("1")},
+			{[]byte("b"), nil},
+			{[]byte("c"), []byte("3")},
+		}
+		if err = txn.PutMany(dbi, kvs, 0); err != nil {
+			return err
+		}
+		for _, kv := range kvs {
+			v, err := txn.Get(dbi, kv.Key)
+			if err != nil {
+				return err
+			}
+			if string(v) != string(kv.Val) {
+				t.Errorf("key %q: %q (!= %q)", kv.Key, v, kv.Val)
+			}
+		}
+
+		kvs = []KV{
+			{[]byte("a"), []byte("x")},
+			{[]byte("d"), []byte("4")},
+			{[]byte("c"), []byte("x")},
+			{[]byte("e"), []byte("5")},
+		}
+		err = txn.PutMany(dbi, kvs, NoOverwrite)
+		berr, ok := err.(*BatchError)
+		if !ok {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if berr.N != len(kvs) {
+			t.Errorf("attempted %d items (!= %d)", berr.N, len(kvs))
+		}
+		for i, e := range berr.Errs {
+			if failed := i == 0 || i == 2; failed != IsErrno(e, KeyExist) {
+				t.Errorf("item %d: unexpected error: %v", i, e)
+			}
+		}
+		if _, err = txn.Get(dbi, []byte("e")); err != nil {
+			t.Errorf("item after failures was not stored: %v", err)
+		}
+
+		err = txn.PutMany(dbi, []KV{{[]byte("f"), nil}, {[]byte("a"), nil}, {[]byte("g"), nil}}, NoOverwrite|StopOnError)
+		berr, ok = err.(*BatchError)
+		if !ok {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if berr.N != 2 || !IsErrno(berr.Errs[1], KeyExist) || berr.Errs[2] != nil {
+			t.Errorf("unexpected batch error: %+v", berr)
+		}
+		if _, err = txn.Get(dbi, []byte("g")); !IsNotFound(err) {
+			t.Errorf("item after StopOnError: %v", err)
+		}
+		// Current, MDBX_RESERVE and MDBX_MULTIPLE
+		for _, flags := range []uint{Current, 0x10000, 0x80000} {
+			err = txn.PutMany(dbi, []KV{{[]byte("h"), []byte("8")}}, flags)
+			if !IsErrnoSys(err, syscall.EINVAL) {
+				t.Errorf("PutMany with flags %#x: %v", flags, err)
+			}
+		}
+		if _, err = txn.Get(dbi, []byte("h")); !IsNotFound(err) {
+			t.Errorf("item stored with an unsupported flag: %v", err)
+		}
+
+		err = txn.DelMany(dbi, [][]byte{[]byte("a"), []byte("zz"), []byte("b")}, 0)
+		berr, ok = err.(*BatchError)
+		if !ok {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if berr.Errs[0] != nil || !IsNotFound(berr.Errs[1]) || berr.Errs[2] != nil {
+			t.Errorf("unexpected batch error: %v", berr)
+		}
+		if _, err = txn.Get(dbi, []byte("b")); !IsNotFound(err) {
+			t.Errorf("deleted key: %v", err)
+		}
+		err = txn.DelMany(dbi, [][]byte{[]byte("c")}, NoDupData)
+		if !IsErrnoSys(err, syscall.EINVAL) {
+			t.Errorf("DelMany with an unsupported flag: %v", err)
+		}
+		if _, err = txn.Get(dbi, []byte("c")); err != nil {
+			t.Errorf("key deleted with an unsupported flag: %v", err)
+		}
+		return txn.DelMany(dbi, [][]byte{[]byte("c"), []byte("d")}, StopOnError)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func BenchmarkTxn_Put_loop(b *testing.B) {
+	env, _, teardown := setup(b)
+	defer teardown()
+
+	kvs := benchKVs(benchGetKeys)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := env.Update(func(txn *Txn) error {
+			dbi, err := txn.OpenRoot(0)
+			if err != nil {
+				return err
+			}
+			for _, kv := range kvs {
+				if err := txn.Put(dbi, kv.Key, kv.Val, 0); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTxn_PutMany(b *testing.B) {
+	env, _, teardown := setup(b)
+	defer teardown()
+
+	kvs := benchKVs(benchGetKeys)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := env.Update(func(txn *Txn) error {
+			dbi, err := txn.OpenRoot(0)
+			if err != nil {
+				return err
+			}
+			return txn.PutMany(dbi, kvs, 0)
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchKVs(n int) []KV {
+	kvs := make([]KV, n)
+	for i := range kvs {
+		kvs[i] = KV{[]byte(fmt.Sprintf("key-%08d", i)), []byte(fmt.Sprintf("val-%08d", i))}
+	}
+	return kvs
+}
+
+func TestTxn_PutReserve(t *testing.T) {
+	env, _, teardown := setup(t)
+	defer teardown()
+
+	var dbi DBI
+	err := env.Update(func(txn *Txn) (err error) {
+		dbi, err = txn.OpenRoot(0)
+		if err != nil {
+			return err
+		}
+		if _, err = txn.PutReserve(dbi, []byte("k"), -1, 0); err != errNegSize {
+			t.Errorf("PutReserve(-1): %v", err)
+		}
+		p, err := txn.PutReserve(dbi, []byte("k"), 5, 0)
+		if err != nil {
+			return err
+		}
+		if len(p) != 5 {
+			t.Errorf("reserved %d bytes", len(p))
+		}
+		copy(p, "hello")
+		if _, err = txn.PutReserve(dbi, []byte("k"), 5, NoOverwrite); !IsErrno(err, KeyExist) {
+			t.Errorf("PutReserve(NoOverwrite): %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = env.View(func(txn *Txn) error {
+		v, err := txn.Get(dbi, []byte("k"))
+		if err == nil && string(v) != "hello" {
+			t.Errorf("value %q", v)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTxn_Stat_Flags(t *testing.T) {
+	env, _, teardown := setup(t)
+	defer teardown()
+
+	err := env.Update(func(txn *Txn) error {
+		dbi, err := txn.OpenDBI("dups", Create|DupSort)
+		if err != nil {
+			return err
+		}
+		flags, err := txn.Flags(dbi)
+		if err != nil {
+			return err
+		}
+		if flags&DupSort == 0 {
+			t.Errorf("flags %#x, want DupSort", flags)
+		}
+		for _, v := range []string{"a", "b", "c"} {
+			if err := txn.Put(dbi, []byte("k"), []byte(v), 0); err != nil {
+				return err
+			}
+		}
+		stat, err := txn.Stat(dbi)
+		if err != nil {
+			return err
+		}
+		if stat.Entries != 3 {
+			t.Errorf("entries %d, want 3", stat.Entries)
+		}
+		if _, err = txn.Stat(DBI(1000)); err == nil {
+			t.Error("Stat of an invalid DBI")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTxn_DelwithVal(t *testing.T) {
+	env, _, teardown := setup(t)
+	defer teardown()
+
+	err := env.Update(func(txn *Txn) error {
+		dbi, err := txn.OpenDBI("dups", Create|DupSort)
+		if err != nil {
+			return err
+		}
+		for _, v := range []string{"a", "b", "c"} {
+			if err = txn.Put(dbi, []byte("k"), []byte(v), 0); err != nil {
+				return err
+			}
+		}
+		if err = txn.DelwithVal(dbi, []byte("k"), []byte("b")); err != nil {
+			return err
+		}
+		var vals []string
+		err = txn.ForEachDup(dbi, []byte("k"), func(v []byte) (bool, error) {
+			vals = append(vals, string(v))
+			return true, nil
+		})
+		if err != nil {
+			return err
+		}
+		if strings.Join(vals, " ") != "a c" {
+			t.Errorf("values after DelwithVal: %q", vals)
+		}
+
+		// a missing value must not delete its neighbour
+		for _, v := range []string{"b", "0"} {
+			if err = txn.DelwithVal(dbi, []byte("k"), []byte(v)); !IsNotFound(err) {
+				t.Errorf("DelwithVal of missing value %q: %v", v, err)
+			}
+		}
+		n, err := txn.CountDups(dbi, []byte("k"))
+		if err != nil {
+			return err
+		}
+		if n != 2 {
+			t.Errorf("%d values after deleting missing ones, want 2", n)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,66 @@
+package mdbx
+
+// Multi is a wrapper for a contiguous page of sorted, fixed-length values
+// passed to Cursor.PutMulti or retrieved using Cursor.Get with the
+// GetMultiple, NextMultiple or PrevMultiple flags.
+//
+// Multi values are only useful in databases opened with DupSort|DupFixed.
+type Multi struct {
+	page   []byte
+	stride int
+}
+
+// WrapMulti converts a page of contiguous values with stride size into a
+// Multi.  WrapMulti panics if len(page) is not a multiple of stride.
+//
+//	_, val, _ := cursor.Get(nil, nil, mdbx.FirstDup)
+//	_, page, _ := cursor.Get(nil, nil, mdbx.GetMultiple)
+//	multi := mdbx.WrapMulti(page, len(val))
+//
+// See mdbx.GetMultiple and mdbx.NextMultiple.
+func WrapMulti(page []byte, stride int) *Multi {
+	if stride <= 0 || len(page)%stride != 0 {
+		panic("incongruent arguments")
+	}
+	return &Multi{page: page, stride: stride}
+}
+
+// Vals returns a slice containing the values in m.  The returned slice has
+// length m.Len() and each item has length m.Stride().
+func (m *Multi) Vals() [][]byte {
+	n := m.Len()
+	ps := make([][]byte, n)
+	for i := 0; i < n; i++ {
+		ps[i] = m.Val(i)
+	}
+	return ps
+}
+
+// Val returns the value at index i.  Val panics if i is out of range.
+func (m *Multi) Val(i int) []byte {
+	off := i * m.stride
+	return m.page[off : off+m.stride : off+m.stride]
+}
+
+// Len returns the number of values in the Multi.
+func (m *Multi) Len() int {
+	return len(m.page) / m.stride
+}
+
+// Stride returns the length of an individual value in the m.
+func (m *Multi) Stride() int {
+	return m.stride
+}
+
+// Size returns the total size of the Multi data and is equal to
+//
+//	m.Len()*m.Stride()
+func (m *Multi) Size() int {
+	return len(m.page)
+}
+
+// Page returns the Multi page data as a raw slice of bytes with length
+// m.Size().
+func (m *Multi) Page() []byte {
+	return m.page[:len(m.page):len(m.page)]
+}
@@ -0,0 +1,37 @@
+package mdbx
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMulti(t *testing.T) {
+	data := []byte("abcdefghijkl")
+	m := WrapMulti(data, 4)
+	if m.Len() != 3 || m.Stride() != 4 || m.Size() != 12 {
+		t.Errorf("len %d, stride %d, size %d", m.Len(), m.Stride(), m.Size())
+	}
+	if !bytes.Equal(m.Page(), data) {
+		t.Errorf("page %q", m.Page())
+	}
+	vals := m.Vals()
+	for i, want := range []string{"abcd", "efgh", "ijkl"} {
+		if string(vals[i]) != want || string(m.Val(i)) != want {
+			t.Errorf("value %d: %q, %q; want %q", i, vals[i], m.Val(i), want)
+		}
+	}
+	if v := m.Val(0); cap(v) != 4 {
+		t.Errorf("value capacity %d, want 4", cap(v))
+	}
+
+	for _, stride := range []int{0, 5} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("WrapMulti(stride %d) did not panic", stride)
+				}
+			}()
+			WrapMulti(data, stride)
+		}()
+	}
+}
@@ -0,0 +1,104 @@
+package mdbx
+
+/*
+#include "mdbx.h"
+*/
+import "C"
+
+import (
+	"fmt"
+)
+
+// Version of the libmdbx headers the package is compiled against.  The
+// library linked with the package must have the same major and minor version,
+// see CheckVersion.
+const (
+	VersionMajor = C.MDBX_VERSION_MAJOR
+	VersionMinor = C.MDBX_VERSION_MINOR
+)
+
+// VersionInfo describes the version of the linked libmdbx.
+//
+// See MDBX_version_info.
+type VersionInfo struct {
+	Major    uint // Major version number
+	Minor    uint // Minor version number
+	Release  uint // Release number of Major.Minor
+	Revision uint // Revision number of Release
+	Git      struct {
+		Datetime string // Committer date, strict ISO-8601 format
+		Tree     string // Commit hash (hexadecimal digits)
+		Commit   string // Tree hash, i.e. digest of the source code
+		Describe string // git-describe string
+	}
+	Sourcery string // Sourcery anchor for pinning
+}
+
+// String returns the version in the major.minor.release.revision form.
+func (v *VersionInfo) String() string {
+	return fmt.Sprintf("%d.%d.%d.%d", v.Major, v.Minor, v.Release, v.Revision)
+}
+
+// Build describes how the linked libmdbx was built.  Some fields may be
+// empty if the information was not provided at build time.
+//
+// See MDBX_build_info.
+type Build struct {
+	Datetime string // Build timestamp (ISO-8601 or __DATE__ __TIME__)
+	Target   string // cpu/arch-system-config triplet
+	Options  string // mdbx-related options
+	Compiler string // Compiler
+	Flags    string // CFLAGS and CXXFLAGS
+}
+
+// Version returns the version of the linked libmdbx.
+//
+// See mdbx_version.
+func Version() *VersionInfo {
+	v := &VersionInfo{
+		Major:    uint(C.mdbx_version.major),
+		Minor:    uint(C.mdbx_version.minor),
+		Release:  uint(C.mdbx_version.release),
+		Revision: uint(C.mdbx_version.revision),
+		Sourcery: C.GoString(C.mdbx_version.sourcery),
+	}
+	v.Git.Datetime = C.GoString(C.mdbx_version.git.datetime)
+	v.Git.Tree = C.GoString(C.mdbx_version.git.tree)
+	v.Git.Commit = C.GoString(C.mdbx_version.git.commit)
+	v.Git.Describe = C.GoString(C.mdbx_version.git.describe)
+	return v
+}
+
+// VersionString returns a string describing the version of the linked
+// libmdbx, like the one of LMDB's mdb_version.
+//
+// See mdbx_version.
+func VersionString() string {
+	v := Version()
+	return fmt.Sprintf("MDBX %s: (%s)", v, v.Git.Datetime)
+}
+
+// BuildInfo returns information about how the linked libmdbx was built.
+//
+// See mdbx_build.
+func BuildInfo() *Build {
+	return &Build{
+		Datetime: C.GoString(C.mdbx_build.datetime),
+		Target:   C.GoString(C.mdbx_build.target),
+		Options:  C.GoString(C.mdbx_build.options),
+		Compiler: C.GoString(C.mdbx_build.compiler),
+		Flags:    C.GoString(C.mdbx_build.flags),
+	}
+}
+
+// CheckVersion returns an error if the major and minor version of the linked
+// libmdbx differ from the version of the headers the package is compiled
+// against.  This may happen when linking a system libmdbx.  NewEnv fails
+// with the same error.
+func CheckVersion() error {
+	v := Version()
+	if v.Major != VersionMajor || v.Minor != VersionMinor {
+		return fmt.Errorf("libmdbx version %s does not match headers version %d.%d", v, VersionMajor, VersionMinor)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package mdbx
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+)
+
+// ErrWriterClosed is returned by Writer.Update after the Writer has been
+// closed.
+var ErrWriterClosed = errors.New("mdbx: writer is closed")
+
+// writerQueue is the number of operations which may wait for a Writer without
+// blocking their callers.
+const writerQueue = 128
+
+// Writer applies write transactions on behalf of other goroutines.  It owns a
+// goroutine locked to its OS thread, so Update may be called from any
+// goroutine, including ones where it isn't known if runtime.LockOSThread has
+// been called.  Operations are applied one at a time in the order they were
+// submitted.
+type Writer struct {
+	env  *Env
+	ops  chan *writeOp
+	mu   sync.RWMutex // held for reading while submitting to ops
+	shut bool
+	done chan struct{}
+}
+
+type writeOp struct {
+	ctx context.Context
+	fn  TxnOp
+	err chan error
+}
+
+// NewWriter starts a Writer for env.  The Writer must be closed before env.
+func (env *Env) NewWriter() *Writer {
+	w := &Writer{
+		env:  env,
+		ops:  make(chan *writeOp, writerQueue),
+		done: make(chan struct{}),
+	}
+	go w.loop()
+	return w
+}
+
+func (w *Writer) loop() {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	defer close(w.done)
+
+	for op := range w.ops {
+		if err := op.ctx.Err(); err != nil {
+			op.err <- err
+			continue
+		}
+		op.err <- w.env.run(false, 0, func(txn *Txn) error {
+			return safelyCall(op.fn, txn)
+		})
+	}
+}
+
+// Update calls fn with a writable transaction on the goroutine of w, like
+// Env.Update, and returns its result.  If ctx is done before fn is started fn
+// is not called and Update returns ctx.Err().  Once started fn runs to
+// completion and Update waits for it regardless of ctx.  If fn panics the
+// transaction is aborted and Update returns an error describing the panic,
+// like Batch, while w keeps applying other operations.
+func (w *Writer) Update(ctx context.Context, fn TxnOp) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	op := &writeOp{ctx: ctx, fn: fn, err: make(chan error, 1)}
+
+	w.mu.RLock()
+	if w.shut {
+		w.mu.RUnlock()
+		return ErrWriterClosed
+	}
+	select {
+	case w.ops <- op:
+		w.mu.RUnlock()
+	case <-ctx.Done():
+		w.mu.RUnlock()
+		return ctx.Err()
+	}
+	return <-op.err
+}
+
+// Close stops accepting operations, waits for the pending ones to be applied
+// and stops the goroutine of w.  Close may be called more than once.
+func (w *Writer) Close() {
+	w.mu.Lock()
+	if !w.shut {
+		w.shut = true
+		close(w.ops)
+	}
+	w.mu.Unlock()
+	<-w.done
+}
@@ -0,0 +1,162 @@
+package mdbx
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWriter(t *testing.T) {
+	env, _, teardown := setup(t)
+	defer teardown()
+
+	w := env.NewWriter()
+	defer w.Close()
+
+	// operations submitted by a goroutine are applied in order
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			key := []byte{byte(g)}
+			for i := 0; i < 50; i++ {
+				i := i
+				err := w.Update(context.Background(), func(txn *Txn) error {
+					dbi, err := txn.OpenRoot(0)
+					if err != nil {
+						return err
+					}
+					v, err := txn.Get(dbi, key)
+					if err == nil && binary.BigEndian.Uint64(v) != uint64(i-1) {
+						t.Errorf("goroutine %d: operation %d applied after %d", g, i, binary.BigEndian.Uint64(v))
+					}
+					val := make([]byte, 8)
+					binary.BigEndian.PutUint64(val, uint64(i))
+					return txn.Put(dbi, key, val, 0)
+				})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	called := false
+	err := w.Update(ctx, func(txn *Txn) error {
+		called = true
+		return nil
+	})
+	if err != context.Canceled || called {
+		t.Errorf("cancelled update: %v (called %v)", err, called)
+	}
+}
+
+func TestWriter_Close(t *testing.T) {
+	env, _, teardown := setup(t)
+	defer teardown()
+
+	w := env.NewWriter()
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go w.Update(context.Background(), func(txn *Txn) error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+
+	const pending = 10
+	ctx, cancel := context.WithCancel(context.Background())
+	results := make(chan error, pending+1)
+	var applied int
+	for i := 0; i < pending; i++ {
+		go func() {
+			results <- w.Update(context.Background(), func(txn *Txn) error {
+				applied++
+				return nil
+			})
+		}()
+	}
+	go func() {
+		results <- w.Update(ctx, func(txn *Txn) error {
+			t.Errorf("cancelled operation was applied")
+			return nil
+		})
+	}()
+	for len(w.ops) < pending+1 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+
+	closed := make(chan struct{})
+	go func() {
+		w.Close()
+		close(closed)
+	}()
+	close(release)
+	<-closed
+	if applied != pending {
+		t.Errorf("applied %d pending operations (!= %d)", applied, pending)
+	}
+	var cancelled int
+	for i := 0; i < pending+1; i++ {
+		if err := <-results; err == context.Canceled {
+			cancelled++
+		} else if err != nil {
+			t.Error(err)
+		}
+	}
+	if cancelled != 1 {
+		t.Errorf("cancelled operations: %d (!= 1)", cancelled)
+	}
+
+	err := w.Update(context.Background(), func(txn *Txn) error { return nil })
+	if err != ErrWriterClosed {
+		t.Errorf("unexpected error: %v", err)
+	}
+	w.Close()
+}
+
+func TestWriter_Panic(t *testing.T) {
+	env, _, teardown := setup(t)
+	defer teardown()
+
+	w := env.NewWriter()
+	defer w.Close()
+
+	err := w.Update(context.Background(), func(txn *Txn) error {
+		dbi, err := txn.OpenRoot(0)
+		if err != nil {
+			return err
+		}
+		if err = txn.Put(dbi, []byte("k"), []byte("v"), 0); err != nil {
+			return err
+		}
+		panic("oops")
+	})
+	if _, ok := err.(panicked); !ok {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// the panicking transaction was aborted and w keeps serving
+	err = w.Update(context.Background(), func(txn *Txn) error {
+		dbi, err := txn.OpenRoot(0)
+		if err != nil {
+			return err
+		}
+		if _, err = txn.Get(dbi, []byte("k")); !IsNotFound(err) {
+			t.Errorf("item of the panicking transaction: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
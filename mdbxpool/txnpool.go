@@ -0,0 +1,239 @@
+/*
+Package mdbxpool provides a pool of readonly transactions for an mdbx.Env.
+
+Beginning a readonly transaction costs a reader slot lookup in the lock table
+and a couple of cgo calls.  A TxnPool keeps transactions which are done with
+in a reset state and renews them on demand, which is cheaper than beginning a
+new transaction for every short read.
+
+Transactions move between goroutines and OS threads while pooled, so the
+environment must be opened without thread local storage for readers, which is
+the default of mdbx.Env.Open (see mdbx.NoTLS).
+*/
+package mdbxpool
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/xzfkiller/mdbx-go/mdbx"
+)
+
+// DefaultMaxIdle is the number of idle transactions kept by a TxnPool when
+// Options.MaxIdle is zero.
+const DefaultMaxIdle = 16
+
+// ErrClosed is returned by TxnPool.Get after the pool has been closed.
+var ErrClosed = errors.New("mdbxpool: pool is closed")
+
+// Options configures a TxnPool.
+type Options struct {
+	// MaxIdle is the maximum number of idle transactions kept by the pool.
+	// Transactions put back into a full pool are aborted.  If MaxIdle is zero
+	// DefaultMaxIdle is used, a negative value disables pooling.
+	MaxIdle int
+
+	// IdleTimeout is the time after which an idle transaction is aborted.
+	// Reset transactions do not hold a snapshot but each one keeps a slot in
+	// the reader table, which is a limited resource shared by all processes
+	// using the environment.  If IdleTimeout is zero idle transactions are
+	// kept until the pool is closed.
+	IdleTimeout time.Duration
+}
+
+// Stats holds counters describing the use of a TxnPool.
+type Stats struct {
+	Hits      uint64 // Transactions returned by Get which were renewed
+	Misses    uint64 // Transactions returned by Get which were begun
+	Discarded uint64 // Transactions aborted by Put because the pool was full
+	Expired   uint64 // Idle transactions aborted after IdleTimeout
+	Idle      int    // Idle transactions in the pool
+}
+
+// HitRate returns the fraction of Get calls served from the pool.
+func (s Stats) HitRate() float64 {
+	n := s.Hits + s.Misses
+	if n == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(n)
+}
+
+type idleTxn struct {
+	txn   *mdbx.Txn
+	since time.Time
+}
+
+// TxnPool recycles readonly transactions through mdbx.Txn.Reset and
+// mdbx.Txn.Renew.  A TxnPool is safe for concurrent use by multiple
+// goroutines.  The pool must be closed before the environment.
+type TxnPool struct {
+	env  *mdbx.Env
+	opts Options
+
+	mu     sync.Mutex
+	idle   []idleTxn // idle transactions, oldest first
+	stats  Stats
+	closed bool
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewTxnPool returns a TxnPool of readonly transactions in env.
+func NewTxnPool(env *mdbx.Env, opts Options) *TxnPool {
+	if opts.MaxIdle == 0 {
+		opts.MaxIdle = DefaultMaxIdle
+	}
+	p := &TxnPool{
+		env:  env,
+		opts: opts,
+		done: make(chan struct{}),
+	}
+	if opts.IdleTimeout > 0 {
+		p.wg.Add(1)
+		go p.expireLoop()
+	}
+	return p
+}
+
+// Get returns a readonly transaction, renewing an idle one if possible.  The
+// transaction must be returned with Put, or aborted.
+func (p *TxnPool) Get() (*mdbx.Txn, error) {
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return nil, ErrClosed
+		}
+		n := len(p.idle)
+		if n == 0 {
+			p.mu.Unlock()
+			txn, err := p.env.BeginTxn(nil, mdbx.Readonly)
+			if err != nil {
+				return nil, err
+			}
+			p.mu.Lock()
+			p.stats.Misses++
+			p.mu.Unlock()
+			return txn, nil
+		}
+		txn := p.idle[n-1].txn
+		p.idle[n-1] = idleTxn{}
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+
+		txn.Pooled = false
+		if err := txn.Renew(); err != nil {
+			// The transaction cannot be reused, try the next one.
+			txn.Abort()
+			continue
+		}
+		p.mu.Lock()
+		p.stats.Hits++
+		p.mu.Unlock()
+		return txn, nil
+	}
+}
+
+// Put returns txn to the pool.  The transaction is reset, it must not be used
+// by the caller afterwards.  If the pool is full or closed txn is aborted.
+func (p *TxnPool) Put(txn *mdbx.Txn) {
+	txn.Reset()
+	txn.RawRead = false
+	txn.Pooled = true
+
+	p.mu.Lock()
+	if p.closed || len(p.idle) >= p.opts.MaxIdle {
+		if !p.closed {
+			p.stats.Discarded++
+		}
+		p.mu.Unlock()
+		txn.Abort()
+		return
+	}
+	p.idle = append(p.idle, idleTxn{txn: txn, since: time.Now()})
+	p.mu.Unlock()
+}
+
+// View calls fn with a readonly transaction from the pool, like
+// mdbx.Env.View.  The transaction is returned to the pool after fn returns,
+// so neither the transaction nor data read with RawRead may be retained.
+func (p *TxnPool) View(fn mdbx.TxnOp) error {
+	txn, err := p.Get()
+	if err != nil {
+		return err
+	}
+	defer p.Put(txn)
+	return txn.RunOp(fn, false)
+}
+
+// Stats returns the counters of the pool.
+func (p *TxnPool) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := p.stats
+	s.Idle = len(p.idle)
+	return s
+}
+
+// Close aborts the idle transactions and stops the expiration of idle
+// transactions.  Transactions put back after Close are aborted.
+func (p *TxnPool) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	idle := p.idle
+	p.idle = nil
+	p.mu.Unlock()
+
+	close(p.done)
+	p.wg.Wait()
+	for _, it := range idle {
+		it.txn.Abort()
+	}
+}
+
+func (p *TxnPool) expireLoop() {
+	defer p.wg.Done()
+	interval := p.opts.IdleTimeout / 2
+	if interval < time.Millisecond {
+		interval = time.Millisecond
+	}
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case now := <-tick.C:
+			p.expire(now)
+		}
+	}
+}
+
+// expire aborts the transactions which have been idle for longer than
+// IdleTimeout at time now.
+func (p *TxnPool) expire(now time.Time) {
+	p.mu.Lock()
+	i := 0
+	for i < len(p.idle) && now.Sub(p.idle[i].since) >= p.opts.IdleTimeout {
+		i++
+	}
+	stale := make([]idleTxn, i)
+	copy(stale, p.idle)
+	n := copy(p.idle, p.idle[i:])
+	for j := n; j < len(p.idle); j++ {
+		p.idle[j] = idleTxn{}
+	}
+	p.idle = p.idle[:n]
+	p.stats.Expired += uint64(i)
+	p.mu.Unlock()
+
+	for _, it := range stale {
+		it.txn.Abort()
+	}
+}
@@ -0,0 +1,148 @@
+package mdbxpool
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/xzfkiller/mdbx-go/mdbx"
+)
+
+// openEnv opens an environment in a temporary directory, both of which are
+// removed when the test ends.
+func openEnv(t *testing.T) *mdbx.Env {
+	path, err := ioutil.TempDir("", "mdbxpool_test")
+	if err != nil {
+		t.Fatalf("Cannot create temporary directory: %s", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(path) })
+	env, err := mdbx.NewEnv()
+	if err != nil {
+		t.Fatalf("Cannot create environment: %s", err)
+	}
+	t.Cleanup(func() { env.Close() })
+	if err = env.SetMaxDBs(4); err != nil {
+		t.Fatalf("Cannot set max dbs: %s", err)
+	}
+	if err = env.Open(path); err != nil {
+		t.Fatalf("Cannot open environment: %s", err)
+	}
+	return env
+}
+
+func put(t *testing.T, env *mdbx.Env, k, v string) {
+	err := env.Update(func(txn *mdbx.Txn) error {
+		dbi, err := txn.OpenRoot(0)
+		if err != nil {
+			return err
+		}
+		return txn.Put(dbi, []byte(k), []byte(v), 0)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTxnPool(t *testing.T) {
+	env := openEnv(t)
+
+	pool := NewTxnPool(env, Options{MaxIdle: 1})
+	defer pool.Close()
+
+	put(t, env, "k", "v1")
+	view := func(want string) {
+		err := pool.View(func(txn *mdbx.Txn) error {
+			dbi, err := txn.OpenRoot(0)
+			if err != nil {
+				return err
+			}
+			v, err := txn.Get(dbi, []byte("k"))
+			if err != nil {
+				return err
+			}
+			if string(v) != want {
+				t.Errorf("value %q (!= %q)", v, want)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	view("v1")
+	put(t, env, "k", "v2")
+	view("v2")
+
+	s := pool.Stats()
+	if s.Hits != 1 || s.Misses != 1 || s.Idle != 1 || s.HitRate() != 0.5 {
+		t.Errorf("unexpected stats: %+v", s)
+	}
+
+	txn1, err := pool.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	txn2, err := pool.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.Put(txn1)
+	pool.Put(txn2)
+	s = pool.Stats()
+	if s.Discarded != 1 || s.Idle != 1 {
+		t.Errorf("unexpected stats: %+v", s)
+	}
+
+	pool.Close()
+	if _, err := pool.Get(); err != ErrClosed {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if s = pool.Stats(); s.Idle != 0 {
+		t.Errorf("idle transactions after Close: %d", s.Idle)
+	}
+}
+
+func TestTxnPool_IdleTimeout(t *testing.T) {
+	env := openEnv(t)
+
+	pool := NewTxnPool(env, Options{IdleTimeout: 20 * time.Millisecond})
+	defer pool.Close()
+
+	txn, err := pool.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.Put(txn)
+	if s := pool.Stats(); s.Idle != 1 {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for pool.Stats().Idle != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("idle transaction did not expire")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if s := pool.Stats(); s.Expired != 1 {
+		t.Errorf("unexpected stats: %+v", s)
+	}
+}
+
+func TestTxnPool_BeginError(t *testing.T) {
+	env, err := mdbx.NewEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer env.Close()
+
+	// transactions cannot begin in an environment which is not open
+	pool := NewTxnPool(env, Options{})
+	defer pool.Close()
+	if _, err := pool.Get(); err == nil {
+		t.Fatal("Get in an environment which is not open")
+	}
+	if s := pool.Stats(); s.Misses != 0 || s.HitRate() != 0 {
+		t.Errorf("unexpected stats: %+v", s)
+	}
+}
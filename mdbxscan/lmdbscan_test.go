@@ -0,0 +1,382 @@
+package mdbxscan
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/xzfkiller/mdbx-go/mdbx"
+)
+
+type errcheck func(err error) (ok bool)
+
+var pIsNil = func(err error) bool { return err == nil }
+
+func TestLMDBScanner_err(t *testing.T) {
+	env := openEnv(t)
+
+	err := env.View(func(txn *mdbx.Txn) (err error) {
+		scanner := New(txn, 123)
+		defer scanner.Close()
+		for scanner.Scan() {
+			t.Error("loop should not execute")
+		}
+		if scanner.Set(nil, nil, mdbx.First) {
+			t.Error("Set returned true")
+		}
+		if scanner.SetNext(nil, nil, mdbx.NextNoDup, mdbx.NextDup) {
+			t.Error("SetNext returned true")
+		}
+		return scanner.Err()
+	})
+	// Deviation: MDBX reports an unknown DBI as BadDBI, not EINVAL.
+	if !mdbx.IsErrno(err, mdbx.BadDBI) {
+		t.Errorf("unexpected error: %q (!= %q)", err, mdbx.BadDBI)
+	}
+}
+
+func TestLMDBScanner_closed(t *testing.T) {
+	env := openEnv(t)
+
+	err := env.View(func(txn *mdbx.Txn) (err error) {
+		dbi, err := txn.OpenRoot(0)
+		if err != nil {
+			return err
+		}
+
+		scanner := New(txn, dbi)
+
+		err = scanner.Err()
+		if err != nil {
+			return err
+		}
+
+		scanner.Close()
+
+		for scanner.Scan() {
+			t.Error("loop should not execute")
+		}
+		return scanner.Err()
+	})
+	if err != errClosed {
+		t.Errorf("unexpected error: %q (!= %q)", err, errClosed)
+	}
+}
+
+func TestLMDBScanner_Scan(t *testing.T) {
+	env := openEnv(t)
+
+	dbi, err := lmdbtestOpenRoot(env, 0)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	items := simpleItemList{
+		{"k0", "v0"},
+		{"k1", "v1"},
+		{"k2", "v2"},
+		{"k3", "v3"},
+		{"k4", "v4"},
+		{"k5", "v5"},
+	}
+	err = lmdbtestPut(env, dbi, items)
+	if err != nil {
+		t.Error(err)
+	}
+	scanned, err := simplescan(env, dbi)
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	if !reflect.DeepEqual(scanned, items) {
+		t.Errorf("unexpected items %q (!= %q)", scanned, items)
+	}
+}
+
+func TestLMDBScanner_Set(t *testing.T) {
+	env := openEnv(t)
+
+	dbi, err := lmdbtestOpenRoot(env, 0)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	items := simpleItemList{
+		{"k0", "v0"},
+		{"k1", "v1"},
+		{"k2", "v2"},
+		{"k3", "v3"},
+		{"k4", "v4"},
+		{"k5", "v5"},
+	}
+	err = lmdbtestPut(env, dbi, items)
+	if err != nil {
+		t.Error(err)
+	}
+
+	var tail simpleItemList
+	err = env.View(func(txn *mdbx.Txn) (err error) {
+		dbi, err := txn.OpenRoot(0)
+		if err != nil {
+			return err
+		}
+		s := New(txn, dbi)
+		defer s.Close()
+
+		s.Set([]byte("k34"), nil, mdbx.SetRange)
+		tail, err = remaining(s)
+		return err
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(tail, items[4:]) {
+		t.Errorf("items: %q (!= %q)", tail, items)
+	}
+}
+
+func TestLMDBScanner_SetNext(t *testing.T) {
+	env := openEnv(t)
+
+	dbi, err := lmdbtestOpenRoot(env, 0)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	items := simpleItemList{
+		{"k0", "v0"},
+		{"k1", "v1"},
+		{"k2", "v2"},
+		{"k3", "v3"},
+		{"k4", "v4"},
+		{"k5", "v5"},
+	}
+	err = lmdbtestPut(env, dbi, items)
+	if err != nil {
+		t.Error(err)
+	}
+
+	var head simpleItemList
+	err = env.View(func(txn *mdbx.Txn) (err error) {
+		dbi, err := txn.OpenRoot(0)
+		if err != nil {
+			return err
+		}
+		s := New(txn, dbi)
+		defer s.Close()
+
+		s.SetNext([]byte("k34"), nil, mdbx.SetRange, mdbx.Prev)
+		head, err = remaining(s)
+		return err
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	// reverse head before testing its value
+	n := len(head)
+	for i := 0; i < n/2; i++ {
+		head[i], head[n-1-i] = head[n-1-i], head[i]
+	}
+
+	if !reflect.DeepEqual(head, items[:5]) {
+		t.Errorf("items: %q (!= %q)", head, items)
+	}
+}
+
+func TestLMDBScanner_Del(t *testing.T) {
+	env := openEnv(t)
+
+	dbi, err := lmdbtestOpenRoot(env, 0)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	items := simpleItemList{
+		{"k0", "v0"},
+		{"k1", "v1"},
+		{"k2", "v2"},
+		{"k3", "v3"},
+		{"k4", "v4"},
+		{"k5", "v5"},
+	}
+	err = lmdbtestPut(env, dbi, items)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = env.Update(func(txn *mdbx.Txn) (err error) {
+		s := New(txn, dbi)
+		defer s.Close()
+		for s.Scan() {
+			err = s.Del(0)
+			if err != nil {
+				return err
+			}
+		}
+		return s.Err()
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	var rem simpleItemList
+	err = env.View(func(txn *mdbx.Txn) (err error) {
+		s := New(txn, dbi)
+		defer s.Close()
+		rem, err = remaining(s)
+		return err
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(rem) != 0 {
+		t.Errorf("items: %q (!= %q)", rem, []string{})
+	}
+}
+
+func TestLMDBScanner_Del_closed(t *testing.T) {
+	env := openEnv(t)
+
+	dbi, err := lmdbtestOpenRoot(env, 0)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	items := simpleItemList{
+		{"k0", "v0"},
+		{"k1", "v1"},
+		{"k2", "v2"},
+		{"k3", "v3"},
+		{"k4", "v4"},
+		{"k5", "v5"},
+	}
+	err = lmdbtestPut(env, dbi, items)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = env.Update(func(txn *mdbx.Txn) (err error) {
+		s := New(txn, dbi)
+		s.Close()
+		return s.Del(0)
+	})
+	if err != errClosed {
+		t.Errorf("unexpected error: %q (!= %q)", err, errClosed)
+	}
+}
+
+func TestLMDBScanner_Cursor_Del(t *testing.T) {
+	env := openEnv(t)
+
+	dbi, err := lmdbtestOpenRoot(env, 0)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	items := simpleItemList{
+		{"k0", "v0"},
+		{"k1", "v1"},
+		{"k2", "v2"},
+		{"k3", "v3"},
+		{"k4", "v4"},
+		{"k5", "v5"},
+	}
+	err = lmdbtestPut(env, dbi, items)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = env.Update(func(txn *mdbx.Txn) (err error) {
+		s := New(txn, dbi)
+		defer s.Close()
+		cur := s.Cursor()
+		for s.Scan() {
+			err = cur.Del(0)
+			if err != nil {
+				return err
+			}
+		}
+		return s.Err()
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	var rem simpleItemList
+	err = env.View(func(txn *mdbx.Txn) (err error) {
+		s := New(txn, dbi)
+		defer s.Close()
+		rem, err = remaining(s)
+		return err
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(rem) != 0 {
+		t.Errorf("items: %q (!= %q)", rem, []string{})
+	}
+}
+
+func simplescan(env *mdbx.Env, dbi mdbx.DBI) (items simpleItemList, err error) {
+	err = env.View(func(txn *mdbx.Txn) (err error) {
+		s := New(txn, dbi)
+		defer s.Close()
+
+		items, err = remaining(s)
+		return err
+	})
+	return items, err
+}
+
+func remaining(s *Scanner) (items simpleItemList, err error) {
+	for s.Scan() {
+		item := &simpleItem{
+			K: string(s.Key()),
+			V: string(s.Val()),
+		}
+		items = append(items, item)
+	}
+	err = s.Err()
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// The helpers below port the parts of lmdb-go's internal lmdbtest package
+// used by the tests above.
+
+type simpleItem struct {
+	K string
+	V string
+}
+
+func (i *simpleItem) String() string { return i.K + "=" + i.V }
+
+type simpleItemList []*simpleItem
+
+func lmdbtestOpenRoot(env *mdbx.Env, flags uint) (dbi mdbx.DBI, err error) {
+	err = env.Update(func(txn *mdbx.Txn) (err error) {
+		dbi, err = txn.OpenRoot(flags)
+		return err
+	})
+	return dbi, err
+}
+
+func lmdbtestPut(env *mdbx.Env, dbi mdbx.DBI, items simpleItemList) error {
+	return env.Update(func(txn *mdbx.Txn) error {
+		for _, item := range items {
+			if err := txn.Put(dbi, []byte(item.K), []byte(item.V), 0); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
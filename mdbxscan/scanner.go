@@ -0,0 +1,140 @@
+/*
+Package mdbxscan provides a wrapper for mdbx.Cursor to simplify iteration.
+It has the API of the lmdbscan package of lmdb-go.
+
+	scanner := mdbxscan.New(txn, dbi)
+	defer scanner.Close()
+	for scanner.Scan() {
+		log.Printf("k=%q v=%q", scanner.Key(), scanner.Val())
+	}
+	return scanner.Err()
+
+Unlike LMDB, MDBX requires every cursor to be closed, so a Scanner must be
+closed even in a readonly transaction.
+*/
+package mdbxscan
+
+import (
+	"errors"
+
+	"github.com/xzfkiller/mdbx-go/mdbx"
+)
+
+// errClosed is returned by Scanner.Err after the Scanner has been closed.
+var errClosed = errors.New("mdbxscan: scanner is closed")
+
+// Scanner is a low level construct for scanning databases inside a
+// transaction.
+type Scanner struct {
+	cur *mdbx.Cursor
+	op  uint
+	set bool
+	key []byte
+	val []byte
+	err error
+}
+
+// New allocates and initializes a Scanner for dbi within txn.  When the
+// Scanner returned by New is no longer needed its Close method must be called.
+func New(txn *mdbx.Txn, dbi mdbx.DBI) *Scanner {
+	s := &Scanner{op: mdbx.Next}
+	s.cur, s.err = txn.OpenCursor(dbi)
+	return s
+}
+
+// Cursor returns the mdbx.Cursor underlying s.  Cursor returns nil if s
+// could not open its cursor or has been closed.
+func (s *Scanner) Cursor() *mdbx.Cursor {
+	return s.cur
+}
+
+// Del deletes the current item, leaving the cursor in place so that the next
+// call to Scan moves to the item that followed it.
+//
+// See mdbx.Cursor.Del.
+func (s *Scanner) Del(flags uint) error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.cur.Del(flags)
+}
+
+// Key returns the key of the current item.
+func (s *Scanner) Key() []byte {
+	return s.key
+}
+
+// Val returns the value of the current item.
+func (s *Scanner) Val() []byte {
+	return s.val
+}
+
+// Set moves the cursor with s.Cursor().Get(k, v, opset) and sets Key, Val
+// and Err accordingly.  The cursor does not move on the next call to Scan,
+// which returns the item found by Set.  Set returns true if an item was found.
+func (s *Scanner) Set(k, v []byte, opset uint) bool {
+	if !s.checkOpen() {
+		return false
+	}
+	s.set = true
+	s.key, s.val, s.err = s.cur.Get(k, v, opset)
+	return s.err == nil
+}
+
+// SetNext is like Set but also changes the op used to move the cursor on
+// following calls to Scan, e.g. to mdbx.NextDup or mdbx.Prev.
+func (s *Scanner) SetNext(k, v []byte, opset, opnext uint) bool {
+	if !s.checkOpen() {
+		return false
+	}
+	ok := s.Set(k, v, opset)
+	s.op = opnext
+	return ok
+}
+
+// Scan moves the cursor to the next item and returns true if an item was
+// found.  Scan returns false at the end of the database or if an error
+// occurred, which is then returned by Err.
+func (s *Scanner) Scan() bool {
+	if !s.checkOpen() {
+		return false
+	}
+	if s.set {
+		s.set = false
+	} else {
+		s.key, s.val, s.err = s.cur.Get(nil, nil, s.op)
+	}
+	return s.err == nil
+}
+
+// checkOpen reports whether s has a cursor, recording errClosed otherwise.
+func (s *Scanner) checkOpen() bool {
+	if s.cur != nil {
+		return true
+	}
+	if s.err == nil || mdbx.IsNotFound(s.err) {
+		s.err = errClosed
+	}
+	return false
+}
+
+// Err returns a non-nil error if and only if the previous call to Scan
+// failed for a reason other than reaching the end of the database.
+func (s *Scanner) Err() error {
+	if mdbx.IsNotFound(s.err) {
+		return nil
+	}
+	return s.err
+}
+
+// Close closes the cursor underlying s.  Close must be called before the
+// transaction terminates, after which Scan returns false.
+func (s *Scanner) Close() {
+	if s.cur != nil {
+		s.cur.Close()
+		s.cur = nil
+	}
+	if s.err == nil || mdbx.IsNotFound(s.err) {
+		s.err = errClosed
+	}
+}
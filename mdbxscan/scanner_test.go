@@ -0,0 +1,193 @@
+package mdbxscan
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/xzfkiller/mdbx-go/mdbx"
+)
+
+// openEnv opens an environment in a temporary directory, both of which are
+// removed when the test ends.
+func openEnv(t *testing.T) *mdbx.Env {
+	path, err := ioutil.TempDir("", "mdbxscan_test")
+	if err != nil {
+		t.Fatalf("Cannot create temporary directory: %s", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(path) })
+	env, err := mdbx.NewEnv()
+	if err != nil {
+		t.Fatalf("Cannot create environment: %s", err)
+	}
+	t.Cleanup(func() { env.Close() })
+	if err = env.SetMaxDBs(4); err != nil {
+		t.Fatalf("Cannot set max dbs: %s", err)
+	}
+	if err = env.Open(path); err != nil {
+		t.Fatalf("Cannot open environment: %s", err)
+	}
+	return env
+}
+
+func setup(t *testing.T) (*mdbx.Env, mdbx.DBI) {
+	env := openEnv(t)
+	var dbi mdbx.DBI
+	err := env.Update(func(txn *mdbx.Txn) (err error) {
+		dbi, err = txn.OpenRoot(0)
+		if err != nil {
+			return err
+		}
+		for _, k := range []string{"a", "b", "c", "d", "e"} {
+			if err := txn.Put(dbi, []byte(k), []byte(k+k), 0); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Cannot put items: %s", err)
+	}
+	return env, dbi
+}
+
+func keys(t *testing.T, s *Scanner) []string {
+	var keys []string
+	for s.Scan() {
+		keys = append(keys, string(s.Key()))
+		if string(s.Val()) != string(s.Key())+string(s.Key()) {
+			t.Errorf("value of %q: %q", s.Key(), s.Val())
+		}
+	}
+	if err := s.Err(); err != nil {
+		t.Error(err)
+	}
+	return keys
+}
+
+func TestScanner_Scan(t *testing.T) {
+	env, dbi := setup(t)
+
+	err := env.View(func(txn *mdbx.Txn) error {
+		s := New(txn, dbi)
+		defer s.Close()
+		if s.Cursor() == nil {
+			t.Fatal("no cursor")
+		}
+		if got, want := keys(t, s), []string{"a", "b", "c", "d", "e"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("keys %q, want %q", got, want)
+		}
+		if s.Scan() {
+			t.Error("Scan after the end of the database")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScanner_Set(t *testing.T) {
+	env, dbi := setup(t)
+
+	err := env.View(func(txn *mdbx.Txn) error {
+		s := New(txn, dbi)
+		defer s.Close()
+
+		s.Set([]byte("c"), nil, mdbx.SetRange)
+		if got, want := keys(t, s), []string{"c", "d", "e"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("keys %q, want %q", got, want)
+		}
+		s.Set([]byte("bb"), nil, mdbx.SetRange)
+		if got, want := keys(t, s), []string{"c", "d", "e"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("keys after restart %q, want %q", got, want)
+		}
+		s.Set([]byte("x"), nil, mdbx.SetKey)
+		if s.Scan() || s.Err() != nil {
+			t.Errorf("Scan of a missing key: %v", s.Err())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScanner_SetNext(t *testing.T) {
+	env, dbi := setup(t)
+
+	err := env.View(func(txn *mdbx.Txn) error {
+		s := New(txn, dbi)
+		defer s.Close()
+
+		s.SetNext(nil, nil, mdbx.Last, mdbx.Prev)
+		if got, want := keys(t, s), []string{"e", "d", "c", "b", "a"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("keys %q, want %q", got, want)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScanner_Del(t *testing.T) {
+	env, dbi := setup(t)
+
+	err := env.Update(func(txn *mdbx.Txn) error {
+		s := New(txn, dbi)
+		defer s.Close()
+		for s.Scan() {
+			if k := string(s.Key()); k == "b" || k == "d" {
+				if err := s.Del(0); err != nil {
+					return err
+				}
+			}
+		}
+		return s.Err()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = env.View(func(txn *mdbx.Txn) error {
+		s := New(txn, dbi)
+		defer s.Close()
+		if got, want := keys(t, s), []string{"a", "c", "e"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("keys %q, want %q", got, want)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScanner_Close(t *testing.T) {
+	env, dbi := setup(t)
+
+	err := env.View(func(txn *mdbx.Txn) error {
+		s := New(txn, dbi)
+		s.Close()
+		if s.Scan() {
+			t.Error("Scan after Close")
+		}
+		if s.Err() != errClosed {
+			t.Errorf("Err after Close: %v", s.Err())
+		}
+		if s.Cursor() != nil {
+			t.Error("cursor not cleared")
+		}
+
+		s = New(txn, mdbx.DBI(1000))
+		defer s.Close()
+		if s.Scan() || s.Err() == nil {
+			t.Errorf("Scan of an invalid DBI: %v", s.Err())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}